@@ -25,6 +25,10 @@ func init() {
 }
 
 func destroyModule(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
 	// Show summary of modules to be destroyed
 	fmt.Printf("Modules to destroy: %s\n", strings.Join(args, ", "))
 	
@@ -43,11 +47,14 @@ func destroyModule(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	recorder := utils.NewJournalRecorder(commandLine())
+	defer recordJournal(recorder)
+
 	// Process each module
 	allSuccessful := true
 	for i, moduleName := range args {
 		fmt.Printf("\n[%d/%d] Destroying module '%s'...\n", i+1, len(args), moduleName)
-		if !destroySingleModule(moduleName) {
+		if !destroySingleModule(moduleName, recorder) {
 			allSuccessful = false
 		}
 	}
@@ -59,7 +66,7 @@ func destroyModule(cmd *cobra.Command, args []string) {
 	}
 }
 
-func destroySingleModule(singularName string) bool {
+func destroySingleModule(singularName string, recorder *utils.JournalRecorder) bool {
 	pluralName := utils.ToSnakeCase(utils.ToPlural(singularName))
 	singularDirName := utils.ToSnakeCase(singularName)
 
@@ -83,6 +90,26 @@ func destroySingleModule(singularName string) bool {
 		moduleExists = false
 	}
 
+	// Record the files about to be removed so base undo can restore them
+	if moduleExists {
+		recorder.TrackDir(moduleDir)
+	}
+	recorder.TrackDir(filepath.Join("test", "app_test", pluralName+"_test"))
+	recorder.Track(
+		filepath.Join("app", "models", singularDirName+".go"),
+		filepath.Join("app", "model", singularDirName+".go"),
+		utils.AppInitPath,
+	)
+	snapshots := []string{
+		pluralDir,
+		singularDir,
+		filepath.Join("test", "app_test", pluralName+"_test"),
+		filepath.Join("app", "models", singularDirName+".go"),
+	}
+	for _, path := range snapshots {
+		recorder.TrackDir(utils.SnapshotPath(path))
+	}
+
 	// Delete module directory if it exists
 	if moduleExists {
 		if err := os.RemoveAll(moduleDir); err != nil {
@@ -126,11 +153,18 @@ func destroySingleModule(singularName string) bool {
 		}
 	}
 
-	// Remove import from base/app/init.go
+	// Remove the snapshots kept for base regen
+	for _, path := range snapshots {
+		if err := utils.RemoveSnapshot(path); err != nil {
+			fmt.Printf("  ⚠️  Warning: %v\n", err)
+		}
+	}
+
+	// Remove import and registration from app/init.go
 	if err := removeModuleFromAppInit(pluralName); err != nil {
-		fmt.Printf("  ⚠️  Warning: Could not remove import from base/app/init.go: %v\n", err)
+		fmt.Printf("  ⚠️  Warning: Could not remove '%s' from %s: %v\n", pluralName, utils.AppInitPath, err)
 	} else {
-		fmt.Printf("  ✅ Removed '%s' from base/app/init.go\n", pluralName)
+		fmt.Printf("  ✅ Removed '%s' from %s\n", pluralName, utils.AppInitPath)
 	}
 
 	if success {
@@ -142,29 +176,22 @@ func destroySingleModule(singularName string) bool {
 
 // removeModuleFromAppInit removes the module from app/init.go
 func removeModuleFromAppInit(moduleName string) error {
-	initGoPath := filepath.Join("app", "init.go")
-
-	// Check if app/init.go exists
-	if _, err := os.Stat(initGoPath); os.IsNotExist(err) {
+	content, err := os.ReadFile(utils.AppInitPath)
+	if os.IsNotExist(err) {
 		return nil // Nothing to remove
 	}
-
-	// Read the file
-	content, err := os.ReadFile(initGoPath)
 	if err != nil {
-		return fmt.Errorf("failed to read app/init.go: %w", err)
+		return fmt.Errorf("failed to read %s: %w", utils.AppInitPath, err)
 	}
 
-	// Remove the import line using the helper function
-	importStr := fmt.Sprintf("\"base/app/%s\"", moduleName)
-	content = utils.RemoveImport(content, importStr)
-
-	// Remove the module initialization using the helper function
-	content = utils.RemoveModuleInitializer(content, moduleName)
+	updated, err := utils.UnregisterAppModule(content, moduleName)
+	if err != nil {
+		return err
+	}
+	reportAppInitDrift(updated)
 
-	// Write back to file
-	if err := os.WriteFile(initGoPath, content, 0644); err != nil {
-		return fmt.Errorf("failed to write app/init.go: %w", err)
+	if err := os.WriteFile(utils.AppInitPath, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", utils.AppInitPath, err)
 	}
 
 	return nil
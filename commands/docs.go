@@ -27,6 +27,10 @@ func init() {
 }
 
 func generateDocs(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		fmt.Printf("Error getting working directory: %v\n", err)
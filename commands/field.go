@@ -0,0 +1,628 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/base-go/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var generateFieldCmd = &cobra.Command{
+	Use:     "field [model] [field:type...]",
+	Aliases: []string{"fields"},
+	Short:   "Add fields to an existing module",
+	Long: `Add fields to an existing module without regenerating it.
+
+The model, request and response structs, ToResponse, ToListResponse, the sortable fields
+and the Create/Update assignments in the service are edited in place; all other code is
+left untouched.
+
+Examples:
+  base g field Post published_at:datetime
+  base g field Post views:int:default=0 author:belongsTo:User
+  base g field User posts:hasMany:Post:foreignKey=author_id`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  addFields,
+}
+
+var removeFieldCmd = &cobra.Command{
+	Use:   "remove-field [model] [field...]",
+	Short: "Remove fields from an existing module",
+	Long: `Remove fields from an existing module without regenerating it.
+
+The fields are removed from the model, request and response structs, ToResponse,
+ToListResponse, the sortable fields and the service assignments. Any other references
+left in the module, and relations of other models using the fields as their foreign key,
+are listed so they can be cleaned up by hand.
+
+Examples:
+  base g remove-field Post subtitle
+  base g remove-field Post author`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  removeFields,
+}
+
+var renameFieldCmd = &cobra.Command{
+	Use:   "rename-field [model] [old] [new]",
+	Short: "Rename a field in an existing module",
+	Long: `Rename a field in an existing module without regenerating it.
+
+The field is renamed in the model, request and response structs, their JSON names, the
+mappers, the sortable fields and the service. Relations of other models that use the field
+as their foreign key, such as the inverse of a belongsTo, are renamed with it. Any other
+references left in the module are listed so they can be updated by hand.
+
+Example:
+  base g rename-field Post subtitle summary`,
+	Args: cobra.ExactArgs(3),
+	Run:  renameField,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{generateFieldCmd, removeFieldCmd, renameFieldCmd} {
+		cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the changes as a diff without writing them")
+		generateCmd.AddCommand(cmd)
+	}
+}
+
+// addFields adds fields to an existing module's model and service.
+func addFields(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	naming := existingModule(args[0])
+	td, err := utils.NewTemplateData(naming.Model, args[1:])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	for _, field := range td.Fields {
+		if field.IsAttachment || field.Type == "translation.Field" ||
+			(field.IsRelation && field.RelationType != "belongs_to" && field.RelationType != "belongs_to_object" && field.RelationType != "has_many") {
+			fmt.Printf("Error: %s cannot be added to an existing module, only plain fields, belongsTo and hasMany relations are supported\n", field.Name)
+			return
+		}
+		if field.IsTree {
+			// The tree methods and routes are new code, not additions to existing code
+			fmt.Printf("Error: %s makes %s a tree, which adds service methods and routes.\n", field.Name, naming.Model)
+			fmt.Printf("Regenerate the module with it instead: base regen %s <fields...> %s\n", naming.Model, strings.Join(args[1:], " "))
+			return
+		}
+		if field.Polymorphic != "" && !field.IsRelation {
+			// Listing by owner changes the service and controller, not only the fields
+			fmt.Printf("Error: %s is a polymorphic relation, which adds service methods and query parameters.\n", field.Polymorphic)
+			fmt.Printf("Regenerate the module with it instead: base regen %s <fields...> %s\n", naming.Model, strings.Join(args[1:], " "))
+			return
+		}
+		if field.IsSecret {
+			// Hashing adds a helper and a Verify method to the service
+			fmt.Printf("Error: %s is a secret, which adds hashing and a Verify%s service method.\n", field.Name, field.Name)
+			fmt.Printf("Regenerate the module with it instead: base regen %s <fields...> %s\n", naming.Model, strings.Join(args[1:], " "))
+			return
+		}
+		if len(field.Transitions) > 0 {
+			fmt.Printf("Error: %s is a state machine, which adds a transition method, events and a route.\n", field.Name)
+			fmt.Printf("Regenerate the module with it instead: base regen %s <fields...> %s\n", naming.Model, strings.Join(args[1:], " "))
+			return
+		}
+	}
+
+	changes := utils.NewChangeSet()
+	if err := planAddFields(changes, naming, args[1:]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if !applyFieldChanges(changes) {
+		return
+	}
+	fmt.Printf("Successfully added %d field(s) to %s\n", len(args)-1, naming.Model)
+}
+
+// existingModule returns the naming of the module name refers to, by its model (Post) or
+// its plural (posts), the way base g names modules
+func existingModule(name string) *utils.NamingConvention {
+	naming := utils.NewNamingConvention(name)
+	if _, err := os.Stat(filepath.Join("app", "models", naming.ModelSnake+".go")); err == nil {
+		return naming
+	}
+	singular := utils.NewNamingConvention(utils.PluralizeClient.Singular(name))
+	if _, err := os.Stat(filepath.Join("app", "models", singular.ModelSnake+".go")); err == nil {
+		return singular
+	}
+	return naming
+}
+
+// fileEdit is an edit of a generated module file. Optional files are skipped when they do
+// not exist, a nil edit only updates the field definitions in the header.
+type fileEdit struct {
+	path     string
+	optional bool
+	edit     func(content []byte) ([]byte, error)
+}
+
+// planAddFields plans adding field definitions to an existing module: its model, service,
+// test helpers, service tests and the sort fields its controller documents are edited on top
+// of any changes already planned for them, and the definitions are added to the headers of
+// all its generated files.
+func planAddFields(changes *utils.ChangeSet, naming *utils.NamingConvention, defs []string) error {
+	td, err := utils.NewTemplateData(naming.Model, defs)
+	if err != nil {
+		return err
+	}
+	fields := td.Fields
+	edits := []fileEdit{
+		{filepath.Join("app", "models", naming.ModelSnake+".go"), false, func(content []byte) ([]byte, error) {
+			return utils.AddModelFields(content, naming, fields)
+		}},
+		{filepath.Join("app", naming.DirName, "service.go"), false, func(content []byte) ([]byte, error) {
+			return utils.AddServiceFields(content, naming, fields)
+		}},
+		{filepath.Join("app", naming.DirName, "validator.go"), true, func(content []byte) ([]byte, error) {
+			return utils.AddValidatorFields(content, naming, fields)
+		}},
+		{filepath.Join("test", "app_test", naming.DirName+"_test", "helpers_test.go"), true, func(content []byte) ([]byte, error) {
+			return utils.AddTestHelperFields(content, naming, fields)
+		}},
+		{filepath.Join("test", "app_test", naming.DirName+"_test", "service_test.go"), true, func(content []byte) ([]byte, error) {
+			return utils.AddServiceTestFields(content, naming, fields)
+		}},
+		controllerEdit(naming),
+	}
+	for _, path := range headerOnlyFiles(naming) {
+		edits = append(edits, fileEdit{path, true, nil})
+	}
+
+	for _, edit := range edits {
+		content, exists, err := changes.Current(edit.path)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if edit.optional {
+				continue
+			}
+			return fmt.Errorf("module %s not found: %s does not exist", naming.Model, edit.path)
+		}
+
+		updated := updateFieldSpec(content, func(existing []string) []string {
+			return append(existing, defs...)
+		})
+		if edit.edit != nil {
+			if updated, err = edit.edit(updated); err != nil {
+				return err
+			}
+		}
+		if err := planEdit(changes, edit.path, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// controllerEdit documents the sort fields of the definitions in the header of a module's
+// controller, once the header holds its current fields
+func controllerEdit(naming *utils.NamingConvention) fileEdit {
+	return fileEdit{filepath.Join("app", naming.DirName, "controller.go"), true, func(content []byte) ([]byte, error) {
+		info, ok := utils.ReadGeneratedHeader(content)
+		if !ok {
+			return content, nil
+		}
+		td, err := utils.NewTemplateData(naming.Model, info.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return utils.UpdateSortDocs(content, naming, td.Fields)
+	}}
+}
+
+// headerOnlyFiles lists the generated files of a module that the field commands only edit
+// the header of, so base regen sees the same field definitions in every file
+func headerOnlyFiles(naming *utils.NamingConvention) []string {
+	return []string{
+		filepath.Join("app", naming.DirName, "module.go"),
+		filepath.Join("test", "app_test", naming.DirName+"_test", "controller_test.go"),
+	}
+}
+
+// planEdit plans new content for path. A file the change set already generates stays a
+// generated file, anything else is an in-place edit.
+func planEdit(changes *utils.ChangeSet, path string, content []byte) error {
+	if changes.Get(path) != nil {
+		_, err := changes.Add(path, content)
+		return err
+	}
+	_, err := changes.AddEdit(path, content)
+	return err
+}
+
+// removeFields removes fields from an existing module's model and service.
+func removeFields(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	naming := existingModule(args[0])
+	modelPath := filepath.Join("app", "models", naming.ModelSnake+".go")
+	modelSrc, err := os.ReadFile(modelPath)
+	if err != nil {
+		fmt.Printf("Error: module %s not found: %v\n", naming.Model, err)
+		return
+	}
+
+	var names []string
+	for _, field := range args[1:] {
+		fieldNames, err := utils.ResolveFieldNames(modelSrc, naming, field)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		names = append(names, fieldNames...)
+	}
+
+	removeDefs := func(defs []string) []string {
+		var kept []string
+		for _, def := range defs {
+			// Definitions that no longer parse cannot name a removed field
+			if field, err := utils.ParseField(def); err != nil || !slices.Contains(names, field.Name) {
+				kept = append(kept, def)
+			}
+		}
+		return kept
+	}
+	changes, err := editModuleFiles(naming, removeDefs, func(path string, content []byte) ([]byte, error) {
+		return utils.RemoveFields(filepath.Base(path), content, naming, names)
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if !applyFieldChanges(changes) {
+		return
+	}
+	fmt.Printf("Successfully removed %s from %s\n", strings.Join(names, ", "), naming.Model)
+	warnFieldReferences(changes, naming, names)
+}
+
+// renameField renames a field in an existing module's model and service.
+func renameField(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	naming := existingModule(args[0])
+	modelPath := filepath.Join("app", "models", naming.ModelSnake+".go")
+	modelSrc, err := os.ReadFile(modelPath)
+	if err != nil {
+		fmt.Printf("Error: module %s not found: %v\n", naming.Model, err)
+		return
+	}
+
+	oldName, newName := utils.ToPascalCase(args[1]), utils.ToPascalCase(args[2])
+	names, err := utils.ResolveFieldNames(modelSrc, naming, args[1])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if _, err := utils.ResolveFieldNames(modelSrc, naming, args[2]); err == nil {
+		fmt.Printf("Error: field %s already exists in %s\n", newName, naming.Model)
+		return
+	}
+
+	// A belongsTo relation renames both the foreign key and the object
+	renames := make(map[string]string)
+	for _, name := range names {
+		switch name {
+		case utils.TrimIdSuffix(oldName):
+			renames[name] = utils.TrimIdSuffix(newName)
+		case utils.TrimIdSuffix(oldName) + "Id":
+			renames[name] = utils.TrimIdSuffix(newName) + "Id"
+		default:
+			renames[name] = newName
+		}
+	}
+
+	renameDefs := func(defs []string) []string {
+		for i, def := range defs {
+			if field, err := utils.ParseField(def); err == nil && slices.Contains(names, field.Name) {
+				defs[i] = renameFieldDef(def, field, args[2])
+			}
+		}
+		return defs
+	}
+	changes, err := editModuleFiles(naming, renameDefs, func(path string, content []byte) ([]byte, error) {
+		return utils.RenameFields(filepath.Base(path), content, naming, renames)
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err := planRelationKeyRenames(changes, naming, renames); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if !applyFieldChanges(changes) {
+		return
+	}
+	for _, name := range names {
+		fmt.Printf("Renamed %s to %s in %s\n", name, renames[name], naming.Model)
+	}
+	column := oldName
+	if _, ok := renames[utils.TrimIdSuffix(oldName)+"Id"]; ok {
+		column = utils.TrimIdSuffix(oldName) + "Id"
+	}
+	fmt.Printf("Note: the database column changes from %s to %s, existing data is not migrated\n",
+		utils.ToSnakeCase(column), utils.ToSnakeCase(renames[column]))
+	warnFieldReferences(changes, naming, names)
+}
+
+// updateFieldSpec rewrites the field definitions recorded in the header of a generated file,
+// so base regen renders the module with its current fields
+func updateFieldSpec(content []byte, update func(defs []string) []string) []byte {
+	info, ok := utils.ReadGeneratedHeader(content)
+	if !ok {
+		return content
+	}
+	info.Fields = update(slices.Clone(info.Fields))
+	return utils.SetGeneratedHeader(content, info)
+}
+
+// renameFieldDef replaces the name in a field definition. A belongsTo relation that inferred
+// its model from the old name keeps that model.
+func renameFieldDef(def string, field utils.Field, newName string) string {
+	parts := strings.Split(def, ":")
+	parts[0] = newName
+	renamed := strings.Join(parts, ":")
+	if field.RelationType != "belongs_to" {
+		return renamed
+	}
+	if renamedField, err := utils.ParseField(renamed); err == nil && renamedField.RelatedModel != field.RelatedModel {
+		parts = slices.Insert(parts, 2, field.RelatedModel)
+		renamed = strings.Join(parts, ":")
+	}
+	return renamed
+}
+
+// renameForeignKeyDef renames the foreign key of a relation to related in a field definition,
+// such as author_id in posts:hasMany:Post:foreignKey=author_id
+func renameForeignKeyDef(def, related string, renames map[string]string) string {
+	field, err := utils.ParseField(def)
+	if err != nil || field.RelatedModel != related || renames[field.ForeignKey] == "" {
+		return def
+	}
+	parts := strings.Split(def, ":")
+	modifiers := strings.Split(parts[len(parts)-1], ",")
+	for i, mod := range modifiers {
+		if key, _, _ := strings.Cut(mod, "="); strings.EqualFold(key, "foreignKey") || strings.EqualFold(key, "foreign_key") {
+			modifiers[i] = key + "=" + utils.ToSnakeCase(renames[field.ForeignKey])
+		}
+	}
+	parts[len(parts)-1] = strings.Join(modifiers, ",")
+	return strings.Join(parts, ":")
+}
+
+// planRelationKeyRenames plans renaming the fields of a model in the foreignKey and references
+// tags of the other models' relations, such as the inverse of a renamed belongsTo, together
+// with the definitions in the headers of their modules
+func planRelationKeyRenames(changes *utils.ChangeSet, naming *utils.NamingConvention, renames map[string]string) error {
+	paths, _ := filepath.Glob(filepath.Join("app", "models", "*.go"))
+	for _, path := range paths {
+		if path == filepath.Join("app", "models", naming.ModelSnake+".go") {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		updated, err := utils.RenameRelationKeys(path, content, naming.Model, renames)
+		if err != nil {
+			fmt.Printf("Warning: could not check %s: %v\n", path, err)
+			continue
+		}
+		if bytes.Equal(updated, content) {
+			continue
+		}
+
+		spec := func(defs []string) []string {
+			for i, def := range defs {
+				defs[i] = renameForeignKeyDef(def, naming.Model, renames)
+			}
+			return defs
+		}
+		info, ok := utils.ReadGeneratedHeader(content)
+		if !ok {
+			if _, err := changes.AddEdit(path, updated); err != nil {
+				return err
+			}
+			continue
+		}
+		moduleChanges, err := editModuleFiles(utils.NewNamingConvention(info.Model), spec, func(file string, content []byte) ([]byte, error) {
+			if file != path {
+				return content, nil
+			}
+			return utils.RenameRelationKeys(path, content, naming.Model, renames)
+		})
+		if err != nil {
+			// A model without its module only has the header of the model file
+			moduleChanges = utils.NewChangeSet()
+			if _, err := moduleChanges.AddEdit(path, updateFieldSpec(updated, spec)); err != nil {
+				return err
+			}
+		}
+		for _, change := range moduleChanges.Changes {
+			if _, err := changes.AddEdit(change.Path, change.After); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// editModuleFiles plans an edit of a module's model file, service, validators and the
+// generated tests that build requests and compare fields. spec updates the field definitions
+// in the header of these files and of the module's other generated files, and the controller
+// documents the sort fields of the updated definitions.
+func editModuleFiles(naming *utils.NamingConvention, spec func(defs []string) []string, edit func(path string, content []byte) ([]byte, error)) (*utils.ChangeSet, error) {
+	changes := utils.NewChangeSet()
+	editFile := func(path string, optional bool) fileEdit {
+		return fileEdit{path, optional, func(content []byte) ([]byte, error) {
+			return edit(path, content)
+		}}
+	}
+	testDir := filepath.Join("test", "app_test", naming.DirName+"_test")
+	files := []fileEdit{
+		editFile(filepath.Join("app", "models", naming.ModelSnake+".go"), false),
+		editFile(filepath.Join("app", naming.DirName, "service.go"), false),
+		editFile(filepath.Join("app", naming.DirName, "validator.go"), true),
+		editFile(filepath.Join(testDir, "helpers_test.go"), true),
+		editFile(filepath.Join(testDir, "service_test.go"), true),
+		controllerEdit(naming),
+	}
+	for _, path := range headerOnlyFiles(naming) {
+		files = append(files, fileEdit{path, true, nil})
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file.path)
+		if os.IsNotExist(err) && file.optional {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("module %s not found: %w", naming.Model, err)
+		}
+		updated := updateFieldSpec(content, spec)
+		if file.edit != nil {
+			if updated, err = file.edit(updated); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := changes.AddEdit(file.path, updated); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// applyFieldChanges writes planned field edits, or prints them with --dry-run.
+// It returns false when nothing was written.
+func applyFieldChanges(changes *utils.ChangeSet) bool {
+	if dryRun {
+		fmt.Println("Dry run: no files were written. Planned changes:")
+		changes.Print(os.Stdout, true)
+		return false
+	}
+
+	recorder := utils.NewJournalRecorder(commandLine())
+	for _, change := range changes.Changes {
+		recorder.Track(change.Path, utils.SnapshotPath(change.Path))
+	}
+	defer recordJournal(recorder)
+
+	if err := changes.Apply(); err != nil {
+		fmt.Printf("Error writing files: %v\n", err)
+		return false
+	}
+	refreshSnapshots(changes)
+	for _, change := range changes.Changes {
+		if !change.IsUnchanged() {
+			fmt.Printf("✅ Updated %s\n", change.Path)
+		}
+	}
+	return true
+}
+
+// refreshSnapshots re-renders the modules whose model the change set edits with the field
+// definitions now in its header, and saves the output as the snapshots of the edited files
+// that have one. base regen then merges against the new fields instead of seeing the field edits
+// as changes of the user.
+func refreshSnapshots(changes *utils.ChangeSet) {
+	manifest, err := utils.LoadTemplateManifest()
+	if err != nil {
+		fmt.Printf("Warning: Could not update snapshots for base regen: %v\n", err)
+		return
+	}
+
+	for _, change := range changes.Changes {
+		if !change.Edit || filepath.Dir(change.Path) != filepath.Join("app", "models") {
+			continue
+		}
+		info, ok := utils.ReadGeneratedHeader(change.After)
+		if !ok {
+			continue
+		}
+		module := renderModule(moduleSpec{Name: info.Model, Fields: info.Fields}, manifest)
+		if module.err != nil {
+			fmt.Printf("Warning: Could not update snapshots of %s for base regen: %v\n", info.Model, module.err)
+			continue
+		}
+		for _, file := range module.files {
+			if changes.Get(file.Path) == nil {
+				continue
+			}
+			if snapshot, err := utils.LoadSnapshot(file.Path); err != nil || snapshot == nil {
+				continue
+			}
+			if err := utils.SaveSnapshot(file.Path, file.Content); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+// warnFieldReferences lists references to the old field names left in the model, the module
+// directory and its tests, and the relations of other models that name them as their key
+func warnFieldReferences(changes *utils.ChangeSet, naming *utils.NamingConvention, names []string) {
+	paths := []string{filepath.Join("app", "models", naming.ModelSnake+".go")}
+	moduleFiles, _ := filepath.Glob(filepath.Join("app", naming.DirName, "*.go"))
+	testFiles, _ := filepath.Glob(filepath.Join("test", "app_test", naming.DirName+"_test", "*.go"))
+	paths = append(paths, moduleFiles...)
+	paths = append(paths, testFiles...)
+
+	var refs []string
+	for _, path := range paths {
+		content, exists, err := changes.Current(path)
+		if err != nil || !exists {
+			continue
+		}
+		found, err := utils.FindFieldReferences(path, content, names)
+		if err != nil {
+			fmt.Printf("Warning: could not check %s: %v\n", path, err)
+			continue
+		}
+		refs = append(refs, found...)
+	}
+
+	models, _ := filepath.Glob(filepath.Join("app", "models", "*.go"))
+	for _, path := range models {
+		if path == paths[0] {
+			continue
+		}
+		content, exists, err := changes.Current(path)
+		if err != nil || !exists {
+			continue
+		}
+		found, err := utils.FindRelationKeys(path, content, naming.Model, names)
+		if err != nil {
+			fmt.Printf("Warning: could not check %s: %v\n", path, err)
+			continue
+		}
+		refs = append(refs, found...)
+	}
+
+	if len(refs) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  %d reference(s) to %s remain and need to be updated by hand:\n", len(refs), strings.Join(names, ", "))
+	for _, ref := range refs {
+		fmt.Printf("  %s\n", ref)
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/base-go/cmd/utils"
+)
+
+// generateInTempProject generates a module in an empty project directory, as base g does
+func generateInTempProject(t *testing.T, spec moduleSpec) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	changes := utils.NewChangeSet()
+	if _, err := planModules(changes, []moduleSpec{spec}); err != nil {
+		t.Fatal(err)
+	}
+	if err := changes.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.SaveSnapshots(changes); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// regenConflicts merges the module rendered with the fields in its model header into its
+// files, as base regen does, and returns the number of conflicts by path
+func regenConflicts(t *testing.T, naming *utils.NamingConvention) map[string]int {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("app", "models", naming.ModelSnake+".go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, ok := utils.ReadGeneratedHeader(content)
+	if !ok {
+		t.Fatal("the model has no generated header")
+	}
+
+	changes := utils.NewChangeSet()
+	if _, err := planModules(changes, []moduleSpec{{Name: info.Model, Fields: info.Fields}}); err != nil {
+		t.Fatal(err)
+	}
+	_, conflicts, err := mergeGenerated(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, n := range conflicts {
+		if n == 0 {
+			delete(conflicts, path)
+		}
+	}
+	return conflicts
+}
+
+// sortDocumented reports whether the module's controller documents sorting by column
+func sortDocumented(t *testing.T, naming *utils.NamingConvention, column string) bool {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("app", naming.DirName, "controller.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.Contains(line, "@Param sort query") && strings.Contains(line, column+",") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRegenAfterFieldEdits(t *testing.T) {
+	naming := utils.NewNamingConvention("Post")
+	generateInTempProject(t, moduleSpec{Name: "Post", Fields: []string{"title:string", "body:text"}})
+
+	// A field of the user's own, next to where the new fields go
+	modelPath := filepath.Join("app", "models", "post.go")
+	content, err := os.ReadFile(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(content), "\tBody      string         `json:\"body\"`\n",
+		"\tBody      string         `json:\"body\"`\n\tNotes     string         `json:\"notes\"`\n", 1)
+	if edited == string(content) {
+		t.Fatalf("Body field not found in %s:\n%s", modelPath, content)
+	}
+	if err := os.WriteFile(modelPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := utils.NewChangeSet()
+	if err := planAddFields(changes, naming, []string{"published_at:datetime", "views:int"}); err != nil {
+		t.Fatal(err)
+	}
+	if !applyFieldChanges(changes) {
+		t.Fatal("the added fields were not written")
+	}
+	if conflicts := regenConflicts(t, naming); len(conflicts) > 0 {
+		t.Errorf("regen after adding fields: conflicts %v", conflicts)
+	}
+	if !sortDocumented(t, naming, "views") {
+		t.Error("the controller does not document sorting by views")
+	}
+
+	changes, err = editModuleFiles(naming, func(defs []string) []string { return defs[:len(defs)-1] },
+		func(path string, content []byte) ([]byte, error) {
+			return utils.RemoveFields(filepath.Base(path), content, naming, []string{"Views"})
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applyFieldChanges(changes) {
+		t.Fatal("the removed field was not written")
+	}
+	if conflicts := regenConflicts(t, naming); len(conflicts) > 0 {
+		t.Errorf("regen after removing a field: conflicts %v", conflicts)
+	}
+	if sortDocumented(t, naming, "views") {
+		t.Error("the controller still documents sorting by the removed views")
+	}
+
+	changes, err = editModuleFiles(naming, func(defs []string) []string {
+		return append([]string{"headline:string"}, defs[1:]...)
+	}, func(path string, content []byte) ([]byte, error) {
+		return utils.RenameFields(filepath.Base(path), content, naming, map[string]string{"Title": "Headline"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !applyFieldChanges(changes) {
+		t.Fatal("the renamed field was not written")
+	}
+	if conflicts := regenConflicts(t, naming); len(conflicts) > 0 {
+		t.Errorf("regen after renaming a field: conflicts %v", conflicts)
+	}
+	if sortDocumented(t, naming, "title") || !sortDocumented(t, naming, "headline") {
+		t.Error("the controller does not document sorting by the renamed headline")
+	}
+}
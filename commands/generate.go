@@ -1,249 +1,723 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/base-go/cmd/utils"
 	"github.com/spf13/cobra"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+)
+
+var (
+	fromSchema   string
+	dryRun       bool
+	force        bool
+	skipExisting bool
+	skipTests    bool
+	verify       bool
+	inverse      bool
 )
 
 var generateCmd = &cobra.Command{
-	Use:     "generate [name] [field:type...]",
+	Use:     "generate [name] [field:type...] [-- name field:type...]",
 	Aliases: []string{"g"},
 	Short:   "Generate a new module",
-	Long:    `Generate a new module with the specified name and fields. Use --admin flag to generate admin interface.`,
-	Args:    cobra.MinimumNArgs(1),
-	Run:     generateModule,
+	Long: `Generate a new module with the specified name and fields. Use --admin flag to generate admin interface.
+
+Several modules can be generated at once by separating them with --. They are rendered
+concurrently, registered in app/init.go in one edit and followed by a single go mod tidy.
+Flags must come before the first --:
+  base g --verify Post title:string -- Comment body:text post:belongsTo
+
+Modules can also be generated from a schema file:
+  base g --from schema.yaml
+
+Use --dry-run to preview the files that would be created or changed without writing anything.
+
+Existing files that differ from the generated version are never overwritten silently: you are
+asked per file whether to overwrite, skip, show a diff or write a .new file alongside.
+Use --force or --skip-existing for non-interactive runs.
+
+The other side of a relation is offered for the related model: a belongsTo adds a hasMany
+field to the parent, a hasMany adds the belongsTo foreign key to the child and the owners listed
+by a polymorphic relation get a hasMany on it. Use --inverse to add them without asking.
+
+Use --verify to type-check the generated packages afterwards. Errors are traced back to the
+template and field that produced them, and the generation can be rolled back.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if force && skipExisting {
+			return fmt.Errorf("--force and --skip-existing cannot be used together")
+		}
+		if fromSchema != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	Run: generateModule,
 }
 
 func init() {
+	generateCmd.Flags().StringVar(&fromSchema, "from", "", "Generate every module described in a YAML or JSON schema file")
+	generateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the files that would be created or changed, with a diff, without writing them")
+	generateCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing files without asking")
+	generateCmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "Keep existing files and only create missing ones")
+	generateCmd.Flags().BoolVar(&skipTests, "skip-tests", false, "Do not generate service and controller tests")
+	generateCmd.Flags().BoolVar(&verify, "verify", false, "Type-check the generated code and offer to roll back on errors")
+	generateCmd.Flags().BoolVar(&inverse, "inverse", false, "Add the other side of belongsTo and hasMany relations to the related models without asking")
 	rootCmd.AddCommand(generateCmd)
 }
 
 // generateModule generates a new module with the specified name and fields.
 func generateModule(cmd *cobra.Command, args []string) {
-	singularName := args[0]
-	fields := args[1:]
-
-	// Create naming convention from the input name
-	naming := utils.NewNamingConvention(singularName)
-
-	// Create directories (plural names in snake_case)
-	dirs := []string{
-		filepath.Join("app", "models"),
-		filepath.Join("app", naming.DirName),
+	// The schema path is relative to where the command was run, not the project root
+	if fromSchema != "" {
+		if abs, err := filepath.Abs(fromSchema); err == nil {
+			fromSchema = abs
+		}
+	}
+	if !enterProjectRoot() {
+		return
 	}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			fmt.Printf("Error creating directory %s: %v\n", dir, err)
+
+	var specs []moduleSpec
+	if fromSchema != "" {
+		schema, err := utils.LoadSchema(fromSchema)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		for _, model := range schema.Models {
+			specs = append(specs, moduleSpec{Name: model.Name, Fields: model.FieldDefs()})
+		}
+	} else {
+		var err error
+		specs, err = splitModuleArgs(args, cmd.ArgsLenAtDash())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			return
 		}
 	}
 
-	// Generate field structs
-	fieldStructs := utils.NewTemplateData(naming.Model, fields)
+	changes := utils.NewChangeSet()
+	namings, err := planModules(changes, specs)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	planInverseRelations(changes, specs)
 
-	// Generate model
-	utils.GenerateFileFromTemplate(
-		filepath.Join("app", "models"),
-		naming.ModelSnake+".go",
-		"model.tmpl",
-		naming,
-		fieldStructs.Fields,
-	)
-
-	// Generate service
-	utils.GenerateFileFromTemplate(
-		filepath.Join("app", naming.DirName),
-		"service.go",
-		"service.tmpl",
-		naming,
-		fieldStructs.Fields,
-	)
-
-	// Generate controller
-	utils.GenerateFileFromTemplate(
-		filepath.Join("app", naming.DirName),
-		"controller.go",
-		"controller.tmpl",
-		naming,
-		fieldStructs.Fields,
-	)
-
-	// Generate module
-	utils.GenerateFileFromTemplate(
-		filepath.Join("app", naming.DirName),
-		"module.go",
-		"module.tmpl",
-		naming,
-		fieldStructs.Fields,
-	)
-
-	// Generate validator
-	utils.GenerateFileFromTemplate(
-		filepath.Join("app", naming.DirName),
-		"validator.go",
-		"validator.tmpl",
-		naming,
-		fieldStructs.Fields,
-	)
-
-	// Generate tests - disabled for now, will be added in future
-	// if err := utils.GenerateTests(naming, fieldStructs); err != nil {
-	// 	fmt.Printf("Error generating tests: %v\n", err)
-	// 	return
-	// }
-
-	// Check if goimports is installed
-	if _, err := exec.LookPath("goimports"); err != nil {
-		fmt.Println("goimports not found, installing...")
-		if err := exec.Command("go", "install", "golang.org/x/tools/cmd/goimports@latest").Run(); err != nil {
-			fmt.Printf("Error installing goimports: %v\n", err)
-			fmt.Println("Please install goimports manually: go install golang.org/x/tools/cmd/goimports@latest")
-			return
-		}
-		fmt.Println("goimports installed successfully")
+	if content, exists, err := changes.Current(utils.AppInitPath); err == nil && exists {
+		reportAppInitDrift(content)
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no files were written. Planned changes:")
+		changes.Print(os.Stdout, true)
+		return
+	}
+
+	if !resolveConflicts(changes) {
+		return
 	}
 
-	// Run goimports on generated files
-	generatedPath := filepath.Join("app", naming.DirName)
+	// Record everything the generation touches, including go mod tidy, for base undo
+	recorder := utils.NewJournalRecorder(commandLine())
+	for _, change := range changes.Changes {
+		recorder.Track(change.Path, utils.SnapshotPath(change.Path))
+	}
+	for _, naming := range namings {
+		recorder.TrackDir(filepath.Join("app", naming.DirName))
+		recorder.TrackDir(filepath.Join("test", "app_test", naming.DirName+"_test"))
+	}
+	recorder.Track("go.mod", "go.sum")
+	// A rolled back generation leaves nothing to undo
+	rolledBack := false
+	defer func() {
+		if !rolledBack {
+			recordJournal(recorder)
+		}
+	}()
 
-	fmt.Println("Running goimports on generated files...")
-	// Run goimports on the generated directory
-	if err := exec.Command("find", generatedPath, "-name", "*.go", "-exec", "goimports", "-w", "{}", ";").Run(); err != nil {
-		fmt.Printf("Error running goimports on %s: %v\n", generatedPath, err)
+	if !writeChanges(changes) {
+		return
 	}
 
-	// Run goimports on the model file
-	modelPath := filepath.Join("app", "models", naming.ModelSnake+".go")
-	if err := exec.Command("goimports", "-w", modelPath).Run(); err != nil {
-		fmt.Printf("Error running goimports on %s: %v\n", modelPath, err)
+	// go mod tidy may add the dependencies of the new code, which a rollback takes back
+	modFiles := make(map[string][]byte)
+	for _, path := range []string{"go.mod", "go.sum"} {
+		if content, err := os.ReadFile(path); err == nil {
+			modFiles[path] = content
+		}
 	}
+	tidyModules()
 
-	// Format all generated files with gofmt
-	fmt.Println("Formatting generated files...")
-	if err := exec.Command("gofmt", "-w", generatedPath).Run(); err != nil {
-		fmt.Printf("Warning: Failed to format generated files in %s: %v\n", generatedPath, err)
+	compiles := !verify || verifyChanges(changes)
+	if !compiles && offerRollback(changes, modFiles) {
+		rolledBack = true
+		return
 	}
-	if err := exec.Command("gofmt", "-w", modelPath).Run(); err != nil {
-		fmt.Printf("Warning: Failed to format model file %s: %v\n", modelPath, err)
+
+	// Snapshots are only saved for files that are kept
+	if err := utils.SaveSnapshots(changes); err != nil {
+		fmt.Printf("Warning: Could not save snapshots for base regen: %v\n", err)
+	}
+	refreshSnapshots(changes)
+	if !compiles {
+		return
 	}
 
-	// Add module to app/init.go
-	if err := addModuleToAppInit(naming.DirName); err != nil {
-		fmt.Printf("Warning: Could not add module to app/init.go: %v\n", err)
-		fmt.Printf("Please manually add: _ \"base/app/%s\" to app/init.go\n", naming.DirName)
+	if len(namings) == 1 {
+		fmt.Printf("Successfully generated %s module\n", namings[0].Model)
 	} else {
-		fmt.Printf("✅ Added module to app/init.go\n")
+		fmt.Printf("Successfully generated %d modules\n", len(namings))
+	}
+}
 
-		// Format init.go after modification
-		initGoPath := filepath.Join("app", "init.go")
-		if err := exec.Command("gofmt", "-w", initGoPath).Run(); err != nil {
-			fmt.Printf("Warning: Failed to format app/init.go: %v\n", err)
-		} else {
-			fmt.Printf("✅ Formatted app/init.go\n")
+// moduleTemplates maps each generated module file to the template that renders it
+var moduleTemplates = []struct {
+	Template string
+	File     string
+}{
+	{"service.tmpl", "service.go"},
+	{"controller.tmpl", "controller.go"},
+	{"module.tmpl", "module.go"},
+	{"validator.tmpl", "validator.go"},
+}
+
+// testTemplates maps each generated test file to the template that renders it
+var testTemplates = []struct {
+	Template string
+	File     string
+}{
+	{"test_helpers.tmpl", "helpers_test.go"},
+	{"service_test.tmpl", "service_test.go"},
+	{"controller_test.tmpl", "controller_test.go"},
+}
+
+// moduleSpec is a module to generate: its name and field definitions
+type moduleSpec struct {
+	Name   string
+	Fields []string
+}
+
+// splitModuleArgs splits the arguments of base g into one spec per module. Modules are
+// separated by "--"; dashAt is the number of arguments before the first one, or -1.
+func splitModuleArgs(args []string, dashAt int) ([]moduleSpec, error) {
+	groups := [][]string{args}
+	if dashAt >= 0 {
+		groups = [][]string{args[:dashAt]}
+		// Cobra drops the first "--", later ones are passed through as arguments
+		rest := args[dashAt:]
+		for {
+			i := slices.Index(rest, "--")
+			if i < 0 {
+				groups = append(groups, rest)
+				break
+			}
+			groups = append(groups, rest[:i])
+			rest = rest[i+1:]
 		}
 	}
 
-	// Run go mod tidy to ensure dependencies are up to date
-	fmt.Println("Running go mod tidy...")
-	if err := exec.Command("go", "mod", "tidy").Run(); err != nil {
-		fmt.Printf("Warning: Failed to run go mod tidy: %v\n", err)
-	} else {
-		fmt.Printf("✅ Dependencies updated\n")
+	var specs []moduleSpec
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		if len(group) == 0 {
+			return nil, fmt.Errorf("expected a module name after --")
+		}
+		for _, arg := range group {
+			if strings.HasPrefix(arg, "-") {
+				return nil, fmt.Errorf("%s is not a field definition, flags must come before the first --", arg)
+			}
+		}
+		model := utils.NewNamingConvention(group[0]).Model
+		if seen[model] {
+			return nil, fmt.Errorf("module %s is listed more than once", model)
+		}
+		seen[model] = true
+		specs = append(specs, moduleSpec{Name: group[0], Fields: group[1:]})
 	}
+	return specs, nil
+}
+
+// plannedFile is a file rendered for a module, with the template and fields it came from
+type plannedFile struct {
+	Path     string
+	Template string
+	Content  []byte
+	Fields   []utils.Field
+}
 
-	fmt.Printf("Successfully generated %s module\n", naming.Model)
+// plannedModule holds everything rendered for one module
+type plannedModule struct {
+	naming *utils.NamingConvention
+	fields []utils.Field
+	files  []plannedFile
+	err    error
 }
 
-// addModuleToAppInit adds the module to app/init.go
-func addModuleToAppInit(moduleName string) error {
-	initGoPath := filepath.Join("app", "init.go")
+// planModules renders the modules concurrently and adds their files to the change set in the
+// order given, followed by a single edit of app/init.go registering all of them. Relations to
+// models that are neither among the modules nor in app/models are reported.
+func planModules(changes *utils.ChangeSet, specs []moduleSpec) ([]*utils.NamingConvention, error) {
+	manifest, err := utils.LoadTemplateManifest()
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if app/init.go exists
-	if _, err := os.Stat(initGoPath); os.IsNotExist(err) {
-		// Create app/init.go if it doesn't exist
-		if err := os.MkdirAll("app", os.ModePerm); err != nil {
-			return fmt.Errorf("failed to create app directory: %w", err)
+	modules := make([]plannedModule, len(specs))
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			modules[i] = renderModule(spec, manifest)
+		}()
+	}
+	wg.Wait()
+
+	var namings []*utils.NamingConvention
+	for i, module := range modules {
+		if module.err != nil {
+			return nil, fmt.Errorf("failed to generate %s: %w", specs[i].Name, module.err)
+		}
+		for _, file := range module.files {
+			change, err := changes.Add(file.Path, file.Content)
+			if err != nil {
+				return nil, err
+			}
+			change.Template = file.Template
+			change.Fields = file.Fields
 		}
+		namings = append(namings, module.naming)
+	}
 
-		content := fmt.Sprintf(`package app
+	// Add the modules to app/init.go
+	current, _, err := changes.Current(utils.AppInitPath)
+	if err != nil {
+		return nil, err
+	}
+	updated := current
+	for _, naming := range namings {
+		registered, err := utils.RegisterAppModule(updated, naming.DirName)
+		if err != nil {
+			fmt.Printf("Warning: Could not add module to %s: %v\n", utils.AppInitPath, err)
+			fmt.Printf("Please manually add: modules[\"%s\"] = %s.Init(deps) to %s\n", naming.DirName, naming.DirName, utils.AppInitPath)
+			continue
+		}
+		updated = registered
+	}
+	if updated != nil {
+		if _, err := changes.AddEdit(utils.AppInitPath, updated); err != nil {
+			return nil, err
+		}
+	}
 
-import (
-	"base/app/%s"
-	"base/core/module"
-)
+	reportMissingRelations(modules)
+	return namings, nil
+}
 
-// AppModules implements module.AppModuleProvider interface
-type AppModules struct{}
+// renderModule renders the model, service, controller, module, validator, manifest templates
+// and tests of one module
+func renderModule(spec moduleSpec, manifest *utils.TemplateManifest) plannedModule {
+	naming := utils.NewNamingConvention(spec.Name)
+	td, err := utils.NewTemplateData(naming.Model, spec.Fields)
+	if err != nil {
+		return plannedModule{naming: naming, err: err}
+	}
+	fields := td.Fields
+	info := utils.NewGeneratedInfo(naming.Model, spec.Fields)
+	module := plannedModule{naming: naming, fields: fields}
+
+	render := func(path, templateName string) bool {
+		file, err := renderFile(path, templateName, naming, fields, info)
+		if err != nil {
+			module.err = err
+			return false
+		}
+		module.files = append(module.files, file)
+		return true
+	}
 
-// GetAppModules returns the list of app modules to initialize
-// This is the only function that needs to be updated when adding new app modules
-func (am *AppModules) GetAppModules(deps module.Dependencies) map[string]module.Module {
-	modules := make(map[string]module.Module)
+	// Generate model
+	if !render(filepath.Join("app", "models", naming.ModelSnake+".go"), "model.tmpl") {
+		return module
+	}
+
+	// Generate service, controller, module and validator (plural names in snake_case)
+	for _, file := range moduleTemplates {
+		if !render(filepath.Join("app", naming.DirName, file.File), file.Template) {
+			return module
+		}
+	}
+
+	// Generate extra files listed in the project's template manifest
+	for _, entry := range manifest.Templates {
+		outputPath, err := entry.OutputPath(naming, fields)
+		if err != nil {
+			module.err = err
+			return module
+		}
+		if !render(outputPath, entry.Template) {
+			return module
+		}
+	}
 
-	// App modules - custom system functionality
-	modules["%s"] = %s.Init(deps)
+	// Generate service and controller tests
+	if !skipTests {
+		testDir := filepath.Join("test", "app_test", naming.DirName+"_test")
+		for _, file := range testTemplates {
+			if !render(filepath.Join(testDir, file.File), file.Template) {
+				return module
+			}
+		}
+	}
 
-	return modules
+	return module
 }
 
-// NewAppModules creates a new AppModules provider
-func NewAppModules() *AppModules {
-	return &AppModules{}
+// renderFile renders a template for path, recording where it came from. Go files are
+// formatted, get their imports fixed and a header with the generator version and field
+// definitions.
+func renderFile(path, templateName string, naming *utils.NamingConvention, fields []utils.Field, info utils.GeneratedInfo) (plannedFile, error) {
+	content, err := utils.RenderTemplate(templateName, naming, fields)
+	if err != nil {
+		return plannedFile{}, err
+	}
+	if strings.HasSuffix(path, ".go") {
+		// Templates that render invalid Go are written as they are, --verify reports the errors
+		content, _ = utils.FormatGoFile(path, content)
+		content = utils.AddGeneratedHeader(content, info)
+	}
+	return plannedFile{Path: path, Template: templateName, Content: content, Fields: fields}, nil
 }
-`, moduleName, moduleName, moduleName)
 
-		if err := os.WriteFile(initGoPath, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to create app/init.go: %w", err)
+// reportMissingRelations warns about relations to models that are neither generated together
+// nor present in app/models
+func reportMissingRelations(modules []plannedModule) {
+	generated := make(map[string]bool)
+	for _, module := range modules {
+		generated[module.naming.Model] = true
+	}
+
+	for _, module := range modules {
+		for _, field := range module.fields {
+			if !field.IsRelation || field.RelatedModel == "" || field.RelationType == "belongs_to_object" {
+				continue
+			}
+			related := field.RelatedModel
+			if generated[related] {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join("app", "models", utils.ToSnakeCase(related)+".go")); err == nil {
+				continue
+			}
+			fmt.Printf("⚠️  %s.%s refers to %s, which is not in app/models; generate it too, e.g. base g %s ... -- %s ...\n",
+				module.naming.Model, field.Name, related, module.naming.Model, related)
 		}
-		return nil
 	}
+}
 
-	// Read existing app/init.go content
-	content, err := os.ReadFile(initGoPath)
-	if err != nil {
-		return fmt.Errorf("failed to read app/init.go: %w", err)
+// inverseRelation is the field that completes a relation on the related model
+type inverseRelation struct {
+	from       string                  // The field declaring the relation, e.g. Post.AuthorId
+	naming     *utils.NamingConvention // The related module, which receives the field
+	def        string                  // Field definition to add, e.g. posts:hasMany:Post:foreignKey=author_id
+	prefixed   string                  // Definition used when the model has a field named like def, e.g. author_posts:...
+	related    string                  // Model on the other side
+	foreignKey string                  // Foreign key on the child model
+	hasMany    bool                    // The field to add is a hasMany, otherwise a belongsTo
+}
+
+// inverseRelations lists the other side of every belongsTo, hasMany and polymorphic relation
+// of the specs
+func inverseRelations(specs []moduleSpec) []inverseRelation {
+	var relations []inverseRelation
+	for _, spec := range specs {
+		naming := utils.NewNamingConvention(spec.Name)
+		td, err := utils.NewTemplateData(naming.Model, spec.Fields)
+		if err != nil {
+			continue // Reported when the module was rendered
+		}
+		// The inverse of a belongsTo is named after the model, e.g. Posts, unless several
+		// belongsTo point at the same model, e.g. AuthorPosts and EditorPosts
+		belongsTo := make(map[string]int)
+		for _, field := range td.Fields {
+			if field.RelationType == "belongs_to" {
+				belongsTo[field.RelatedModel]++
+			}
+		}
+		for _, field := range td.Fields {
+			if field.RelatedModel == naming.Model {
+				continue // Self-references such as trees are complete on their own
+			}
+			// Comment commentable:polymorphic:Post gives Post comments:hasMany:Comment:polymorphic=commentable
+			for _, owner := range field.PolymorphicOwners {
+				relations = append(relations, inverseRelation{
+					from:       naming.Model + "." + field.Polymorphic,
+					naming:     utils.NewNamingConvention(owner),
+					def:        naming.DirName + ":hasMany:" + naming.Model + ":polymorphic=" + utils.ToSnakeCase(field.Polymorphic),
+					related:    naming.Model,
+					foreignKey: field.Polymorphic + "Id",
+					hasMany:    true,
+				})
+			}
+
+			switch field.RelationType {
+			case "belongs_to":
+				// Post author:belongsTo:User gives User posts:hasMany:Post:foreignKey=author_id
+				parent := utils.NewNamingConvention(field.RelatedModel)
+				def := ":hasMany:" + naming.Model
+				if field.Name != parent.Model+"Id" {
+					def += ":foreignKey=" + utils.ToSnakeCase(field.Name)
+				}
+				prefixed := utils.ToSnakeCase(utils.TrimIdSuffix(field.Name)) + "_" + naming.DirName + def
+				if belongsTo[field.RelatedModel] > 1 {
+					def = prefixed
+				} else {
+					def = naming.DirName + def
+				}
+				relations = append(relations, inverseRelation{
+					from:       naming.Model + "." + field.Name,
+					naming:     parent,
+					def:        def,
+					prefixed:   prefixed,
+					related:    naming.Model,
+					foreignKey: field.Name,
+					hasMany:    true,
+				})
+			case "has_many":
+				if field.Polymorphic != "" {
+					continue // The owned model declares the polymorphic relation and its owners
+				}
+				// User posts:hasMany:Post gives Post user:belongsTo:User
+				child := utils.NewNamingConvention(field.RelatedModel)
+				foreignKey := field.ForeignKey
+				if foreignKey == "" {
+					foreignKey = naming.Model + "Id"
+				}
+				relations = append(relations, inverseRelation{
+					from:       naming.Model + "." + field.Name,
+					naming:     child,
+					def:        utils.ToSnakeCase(utils.TrimIdSuffix(foreignKey)) + ":belongsTo:" + naming.Model,
+					related:    naming.Model,
+					foreignKey: foreignKey,
+				})
+			}
+		}
 	}
+	return relations
+}
+
+// planInverseRelations adds the other side of the relations of the specs to the related
+// models, with --inverse or when confirmed. Relations whose other side exists are left alone.
+func planInverseRelations(changes *utils.ChangeSet, specs []moduleSpec) {
+	reader := bufio.NewReader(os.Stdin)
+	for _, relation := range inverseRelations(specs) {
+		modelPath := filepath.Join("app", "models", relation.naming.ModelSnake+".go")
+		content, exists, err := changes.Current(modelPath)
+		if err != nil || !exists {
+			continue // Missing models are reported by planModules
+		}
 
-	contentStr := string(content)
+		var found bool
+		if relation.hasMany {
+			name, err := utils.HasManyField(content, relation.naming, relation.related, relation.foreignKey)
+			if err != nil {
+				fmt.Printf("Warning: could not check %s: %v\n", modelPath, err)
+				continue
+			}
+			found = name != ""
+		} else {
+			_, err := utils.ResolveFieldNames(content, relation.naming, relation.foreignKey)
+			found = err == nil
+		}
+		if found {
+			continue
+		}
+		// Posts may already hold the posts of another relation
+		if field, err := utils.ParseField(relation.def); err == nil && relation.prefixed != "" {
+			if _, err := utils.ResolveFieldNames(content, relation.naming, field.Name); err == nil {
+				relation.def = relation.prefixed
+			}
+		}
 
-	// Check if module already exists
-	moduleInit := fmt.Sprintf("modules[\"%s\"] = %s.Init(deps)", moduleName, moduleName)
-	if strings.Contains(contentStr, moduleInit) {
-		return nil // Already added
+		if !inverse {
+			if dryRun || force || skipExisting {
+				fmt.Printf("💡 %s has no field for %s. Use --inverse to add %s\n", relation.naming.Model, relation.from, relation.def)
+				continue
+			}
+			fmt.Printf("Add %s to %s for %s? [y/N] ", relation.def, relation.naming.Model, relation.from)
+			response, _ := reader.ReadString('\n')
+			if answer := strings.ToLower(strings.TrimSpace(response)); answer != "y" && answer != "yes" {
+				continue
+			}
+		}
+
+		if err := planAddFields(changes, relation.naming, []string{relation.def}); err != nil {
+			fmt.Printf("Warning: could not add %s to %s: %v\n", relation.def, relation.naming.Model, err)
+			continue
+		}
+		fmt.Printf("↔️  Adding %s to %s for %s\n", relation.def, relation.naming.Model, relation.from)
 	}
+}
 
-	// Add import if not exists using the proper AddImport function
-	importLine := fmt.Sprintf("\"base/app/%s\"", moduleName)
-	contentBytes, importAdded := utils.AddImport([]byte(contentStr), importLine)
-	if importAdded {
-		contentStr = string(contentBytes)
+// resolveConflicts decides what happens to generated files that would overwrite existing,
+// different content. It returns false when the user cancels the generation.
+func resolveConflicts(changes *utils.ChangeSet) bool {
+	conflicts := changes.Conflicts()
+	if len(conflicts) == 0 || force {
+		return true
 	}
 
-	// Add module initialization
-	// Find the return modules line
-	returnIndex := strings.Index(contentStr, "return modules")
-	if returnIndex == -1 {
-		return fmt.Errorf("could not find 'return modules' in app/init.go")
+	if skipExisting {
+		for _, change := range conflicts {
+			fmt.Printf("Skipping existing %s\n", change.Path)
+			changes.Remove(change)
+		}
+		return true
 	}
 
-	// Insert the module initialization before return
-	insertPoint := returnIndex - 1
-	caser := cases.Title(language.English)
-	moduleInitLine := fmt.Sprintf("\n\t// %s module\n\t%s\n", caser.String(moduleName), moduleInit)
-	contentStr = contentStr[:insertPoint] + moduleInitLine + contentStr[insertPoint:]
+	fmt.Printf("%d existing file(s) differ from the generated version.\n", len(conflicts))
+	reader := bufio.NewReader(os.Stdin)
+	overwriteAll := false
 
-	// Write back to file
-	if err := os.WriteFile(initGoPath, []byte(contentStr), 0644); err != nil {
-		return fmt.Errorf("failed to write app/init.go: %w", err)
+	for _, change := range conflicts {
+		if overwriteAll {
+			continue
+		}
+
+	prompt:
+		for {
+			fmt.Printf("%s exists. [o]verwrite, [s]kip, [d]iff, [n]ew (write %s.new), overwrite [a]ll, [q]uit? [s] ", change.Path, filepath.Base(change.Path))
+			response, err := reader.ReadString('\n')
+			if err != nil && response == "" {
+				fmt.Println("\nNo answer available. Use --force or --skip-existing to run non-interactively.")
+				return false
+			}
+
+			switch strings.ToLower(strings.TrimSpace(response)) {
+			case "o", "overwrite":
+				break prompt
+			case "", "s", "skip":
+				changes.Remove(change)
+				break prompt
+			case "d", "diff":
+				fmt.Print(change.Diff())
+			case "n", "new":
+				if err := changes.Redirect(change, change.Path+".new"); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					return false
+				}
+				break prompt
+			case "a", "all":
+				overwriteAll = true
+				break prompt
+			case "q", "quit":
+				fmt.Println("Operation cancelled.")
+				return false
+			default:
+				fmt.Println("Please answer o, s, d, n, a or q.")
+			}
+		}
+	}
+
+	return true
+}
+
+// writeChanges applies the change set and reports what was written
+func writeChanges(changes *utils.ChangeSet) bool {
+	if err := changes.Apply(); err != nil {
+		fmt.Printf("Error writing generated files: %v\n", err)
+		return false
+	}
+
+	for _, change := range changes.Changes {
+		switch {
+		case change.IsUnchanged():
+			fmt.Printf("Unchanged %s\n", change.Path)
+		case change.Edit:
+			fmt.Printf("✅ Updated %s\n", change.Path)
+		default:
+			fmt.Printf("Generated %s\n", change.Path)
+		}
 	}
+	return true
+}
 
-	return nil
+// tidyModules updates go.mod and go.sum for the dependencies of the generated code
+func tidyModules() {
+	fmt.Println("Running go mod tidy...")
+	if err := exec.Command("go", "mod", "tidy").Run(); err != nil {
+		fmt.Printf("Warning: Failed to run go mod tidy: %v\n", err)
+	} else {
+		fmt.Printf("✅ Dependencies updated\n")
+	}
+}
+
+// verifyChanges type-checks the generated packages and reports whether they compile
+func verifyChanges(changes *utils.ChangeSet) bool {
+	fmt.Println("Verifying generated code...")
+	errs, err := utils.VerifyChanges(changes)
+	if err != nil {
+		fmt.Printf("Warning: Could not verify generated code: %v\n", err)
+		return true
+	}
+	if len(errs) == 0 {
+		fmt.Println("✅ Generated code compiles")
+		return true
+	}
+
+	fmt.Printf("❌ Generated code has %d error(s):\n", len(errs))
+	for _, compileErr := range errs {
+		fmt.Printf("  %s\n", compileErr)
+	}
+	return false
+}
+
+// offerRollback asks whether to roll back the generated files and go.mod and go.sum, as they
+// were before go mod tidy. It returns true when the changes were rolled back.
+func offerRollback(changes *utils.ChangeSet, modFiles map[string][]byte) bool {
+	fmt.Print("Roll back the generated files? [y/N] ")
+	response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(response)) {
+	case "y", "yes":
+		if err := changes.Rollback(); err != nil {
+			fmt.Printf("Error rolling back: %v\n", err)
+			return false
+		}
+		for path, content := range modFiles {
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				fmt.Printf("Error restoring %s: %v\n", path, err)
+				return false
+			}
+		}
+		fmt.Println("↩️  Generated files rolled back")
+		return true
+	default:
+		fmt.Println("Generated files were kept")
+		return false
+	}
+}
+
+// reportAppInitDrift warns about registrations in app/init.go that do not follow the generated layout
+func reportAppInitDrift(content []byte) {
+	drift, err := utils.AppInitDrift(content)
+	if err != nil {
+		fmt.Printf("⚠️  Could not check %s: %v\n", utils.AppInitPath, err)
+		return
+	}
+	if len(drift) == 0 {
+		return
+	}
+	fmt.Printf("⚠️  %s differs from the generated layout and may need fixing by hand:\n", utils.AppInitPath)
+	for _, problem := range drift {
+		fmt.Printf("  %s\n", problem)
+	}
 }
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/base-go/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	forceUndo        bool
+	showHistoryFiles bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Revert the last generate, regen, destroy or scheduler operation",
+	Long: `Revert the last operation recorded in .base/journal: files that were created are removed,
+and files that were modified or deleted are restored.
+
+Undo refuses to run when any of those files changed since the operation. Use --force to
+revert them anyway, discarding the later edits.`,
+	Args: cobra.NoArgs,
+	Run:  undoLastOperation,
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the operations recorded in .base/journal",
+	Args:  cobra.NoArgs,
+	Run:   showHistory,
+}
+
+func init() {
+	undoCmd.Flags().BoolVarP(&forceUndo, "force", "f", false, "Revert even if files were edited since the operation")
+	historyCmd.Flags().BoolVar(&showHistoryFiles, "files", false, "List the files changed by each operation")
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// commandLine returns the command as it was invoked, for the journal
+func commandLine() string {
+	return strings.Join(append([]string{"base"}, os.Args[1:]...), " ")
+}
+
+// recordJournal appends the changes seen by recorder to the journal so they can be undone
+func recordJournal(recorder *utils.JournalRecorder) {
+	if _, err := recorder.Commit(); err != nil {
+		fmt.Printf("Warning: Could not record this operation in %s: %v\n", utils.JournalPath, err)
+	}
+}
+
+// undoLastOperation reverts the newest journal entry
+func undoLastOperation(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	entries, err := utils.LoadJournal()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("Nothing to undo.")
+		return
+	}
+
+	entry := entries[len(entries)-1]
+	fmt.Printf("Last operation: %s (%s)\n", entry.Command, entry.Time.Local().Format("2006-01-02 15:04"))
+	printJournalFiles(entry)
+
+	if edited := entry.EditedSince(); len(edited) > 0 {
+		if !forceUndo {
+			fmt.Println("❌ Cannot undo, files changed since the operation:")
+			for _, line := range edited {
+				fmt.Printf("  %s\n", line)
+			}
+			fmt.Println("Use --force to revert anyway and discard those changes.")
+			return
+		}
+		fmt.Printf("⚠️  Discarding later changes to %d file(s)\n", len(edited))
+	}
+
+	fmt.Print("Revert these changes? [Y/n] ")
+	response, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && response == "" {
+		fmt.Printf("Error reading input: %v\n", err)
+		return
+	}
+	response = strings.TrimSpace(strings.ToLower(response))
+	if response != "" && response != "y" {
+		fmt.Println("Operation cancelled.")
+		return
+	}
+
+	if err := entry.Revert(forceUndo); err != nil {
+		fmt.Printf("Error reverting: %v\n", err)
+		return
+	}
+	if err := utils.DropLastJournalEntry(); err != nil {
+		fmt.Printf("Error updating %s: %v\n", utils.JournalPath, err)
+		return
+	}
+	fmt.Printf("✅ Undid: %s\n", entry.Command)
+}
+
+// showHistory lists the journal entries, newest first
+func showHistory(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	entries, err := utils.LoadJournal()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("No operations recorded yet.")
+		return
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		fmt.Printf("%3d  %s  %s  (%s)\n", i+1, entry.Time.Local().Format("2006-01-02 15:04"), entry.Command, entry.Summary())
+		if showHistoryFiles {
+			printJournalFiles(entry)
+		}
+	}
+}
+
+// printJournalFiles lists the files of an entry with their action
+func printJournalFiles(entry *utils.JournalEntry) {
+	for _, file := range entry.Files {
+		fmt.Printf("       %-9s %s\n", file.Action, file.Path)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/base-go/cmd/utils"
 	"github.com/base-go/cmd/version"
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/module"
 )
 
 func min(a, b int) int {
@@ -20,15 +21,32 @@ func min(a, b int) int {
 	return b
 }
 
+var newModulePath string
+
 var newCmd = &cobra.Command{
 	Use:   "new [project_name]",
 	Short: "Create a new project",
-	Long:  `Create a new project by cloning the base repository and setting up the directory.`,
-	Args:  cobra.ExactArgs(1),
-	Run:   createNewProject,
+	Long: `Create a new project by cloning the base repository and setting up the directory.
+
+Use --module to give the project its own Go module path. go.mod and every import of the
+framework are rewritten, e.g.:
+  base new shop --module github.com/acme/shop`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if newModulePath != "" {
+			if err := module.CheckPath(newModulePath); err != nil {
+				return fmt.Errorf("invalid module path: %w", err)
+			}
+		}
+		return nil
+	},
+	Run: createNewProject,
 }
 
 func init() {
+	newCmd.Flags().StringVar(&newModulePath, "module", "", "Go module path for the project (default \""+utils.DefaultModulePath+"\")")
 	rootCmd.AddCommand(newCmd)
 }
 
@@ -159,6 +177,23 @@ func createNewProject(cmd *cobra.Command, args []string) {
 		fmt.Printf("Error getting absolute path: %v\n", err)
 		return
 	}
+	// Give the project its own module path
+	if newModulePath != "" {
+		oldModulePath, err := utils.ReadModulePath(projectName)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if oldModulePath != newModulePath {
+			changed, err := utils.RewriteModulePath(projectName, oldModulePath, newModulePath)
+			if err != nil {
+				fmt.Printf("Error setting module path: %v\n", err)
+				return
+			}
+			fmt.Printf("✅ Module path set to %s (%d files updated)\n", newModulePath, changed)
+		}
+	}
+
 	fmt.Printf("New project '%s' created successfully at %s\n", projectName, absPath)
 	// Copy .env.sample to .env
 	fmt.Println("Copying .env.sample to .env...")
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/base-go/cmd/utils"
+	"github.com/base-go/cmd/version"
+	"github.com/spf13/cobra"
+)
+
+var regenCmd = &cobra.Command{
+	Use:   "regen [name] [field:type...]",
+	Short: "Regenerate a module with the current templates, keeping your changes",
+	Long: `Regenerate a module with the templates of this version of the CLI and merge the result
+into the existing files.
+
+Each file is merged three ways: the output it was originally generated with (kept in
+.base/generated) is the base, the file as it is now holds your changes, and the newly
+generated output holds the template changes. Where both changed the same lines, the file
+gets conflict markers to resolve by hand.
+
+The fields are read from the header of the model file. Modules generated before headers
+were written need their fields passed explicitly; without a snapshot every difference is
+marked as a conflict. Modules without generated tests, e.g. from base g --skip-tests, are
+regenerated without tests.
+
+Examples:
+  base regen Post
+  base regen Post title:string body:text --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  regenerateModule,
+}
+
+func init() {
+	regenCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the merged files as a diff without writing them")
+	regenCmd.Flags().BoolVar(&skipTests, "skip-tests", false, "Do not regenerate service and controller tests")
+	rootCmd.AddCommand(regenCmd)
+}
+
+// regenerateModule re-renders a module and merges the output into its files
+func regenerateModule(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	naming := existingModule(args[0])
+	model, fields := naming.Model, args[1:]
+	if len(fields) == 0 {
+		modelPath := filepath.Join("app", "models", naming.ModelSnake+".go")
+		content, err := os.ReadFile(modelPath)
+		if err != nil {
+			fmt.Printf("Error: module %s not found: %v\n", naming.Model, err)
+			return
+		}
+		info, ok := utils.ReadGeneratedHeader(content)
+		if !ok {
+			fmt.Printf("Error: %s does not record the fields it was generated with.\n", modelPath)
+			fmt.Printf("Pass them explicitly, e.g.: base regen %s title:string body:text\n", naming.Model)
+			return
+		}
+		model, fields = info.Model, info.Fields
+		fmt.Printf("Regenerating %s, generated by base %s with: %s\n", info.Model, info.Version, strings.Join(info.Fields, " "))
+	}
+
+	// A module generated with --skip-tests stays without tests
+	if !skipTests && !hasModuleTests(naming) {
+		skipTests = true
+		fmt.Printf("%s has no generated tests, regenerating it without tests\n", naming.Model)
+	}
+
+	changes := utils.NewChangeSet()
+	if _, err := planModules(changes, []moduleSpec{{Name: model, Fields: fields}}); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	rendered, conflicts, err := mergeGenerated(changes)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if dryRun {
+		fmt.Println("Dry run: no files were written. Planned changes:")
+		changes.Print(os.Stdout, true)
+		return
+	}
+
+	recorder := utils.NewJournalRecorder(commandLine())
+	for _, change := range changes.Changes {
+		recorder.Track(change.Path, utils.SnapshotPath(change.Path))
+	}
+	defer recordJournal(recorder)
+
+	if err := changes.Apply(); err != nil {
+		fmt.Printf("Error writing files: %v\n", err)
+		return
+	}
+	// The new output is the base of the next merge, whatever the merge left in the file
+	for path, content := range rendered {
+		if err := utils.SaveSnapshot(path, content); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+
+	total := 0
+	for _, change := range changes.Changes {
+		switch {
+		case conflicts[change.Path] > 0:
+			fmt.Printf("⚠️  %d conflict(s) in %s\n", conflicts[change.Path], change.Path)
+			total += conflicts[change.Path]
+		case change.IsUnchanged():
+			fmt.Printf("Unchanged %s\n", change.Path)
+		case change.IsNew():
+			fmt.Printf("Generated %s\n", change.Path)
+		default:
+			fmt.Printf("✅ Updated %s\n", change.Path)
+		}
+	}
+
+	if total > 0 {
+		fmt.Printf("Regenerated %s with %d conflict(s). Resolve the sections between <<<<<<< and >>>>>>> by hand.\n", naming.Model, total)
+		return
+	}
+	fmt.Printf("Successfully regenerated %s with base %s\n", naming.Model, version.Version)
+}
+
+// hasModuleTests reports whether any of the generated test files of a module exists
+func hasModuleTests(naming *utils.NamingConvention) bool {
+	for _, test := range testTemplates {
+		if _, err := os.Stat(filepath.Join("test", "app_test", naming.DirName+"_test", test.File)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeGenerated replaces each planned generated file that already exists with a three-way
+// merge of its snapshot, its current content and the new output. It returns the new output
+// by path, to be saved as the next snapshots, and the number of conflicts per path.
+func mergeGenerated(changes *utils.ChangeSet) (map[string][]byte, map[string]int, error) {
+	rendered := make(map[string][]byte)
+	conflicts := make(map[string]int)
+	labels := utils.MergeLabels{Ours: "current", Theirs: "base " + version.Version}
+
+	for _, change := range changes.Changes {
+		if change.Template == "" {
+			continue
+		}
+		rendered[change.Path] = change.After
+		if change.IsNew() {
+			continue
+		}
+
+		base, err := utils.LoadSnapshot(change.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if base == nil {
+			fmt.Printf("No snapshot of %s, every difference is marked as a conflict\n", change.Path)
+		}
+
+		merged, n := utils.Merge3(base, change.Before, change.After, labels)
+		if n == 0 && strings.HasSuffix(change.Path, ".go") {
+			merged, _ = utils.FormatGoFile(change.Path, merged)
+		}
+		change.After = merged
+		change.Edit = true
+		conflicts[change.Path] = n
+	}
+	return rendered, conflicts, nil
+}
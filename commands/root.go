@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
+	"github.com/base-go/cmd/utils"
 	"github.com/base-go/cmd/version"
 	"github.com/spf13/cobra"
 )
@@ -35,6 +37,33 @@ It provides scaffolding, code generation, and development utilities.`,
 	},
 }
 
+// enterProjectRoot changes into the root of the Base project containing the working directory,
+// so commands can be run from any subdirectory. It prints an error and returns false when the
+// working directory is not inside a project.
+func enterProjectRoot() bool {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error getting working directory: %v\n", err)
+		return false
+	}
+
+	root, err := utils.FindProjectRoot(cwd)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Run this command from inside your Base project.")
+		return false
+	}
+
+	if root != cwd {
+		if err := os.Chdir(root); err != nil {
+			fmt.Printf("Error changing to project root %s: %v\n", root, err)
+			return false
+		}
+		fmt.Printf("📁 Using project root %s\n", root)
+	}
+	return true
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
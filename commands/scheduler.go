@@ -100,6 +100,10 @@ func generateTask(cmd *cobra.Command, args []string) {
 	moduleName := args[0]
 	taskName := args[1]
 
+	if !enterProjectRoot() {
+		return
+	}
+
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -107,14 +111,6 @@ func generateTask(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// Check if we're in a Base Framework project (look for main.go)
-	mainGoPath := filepath.Join(cwd, "main.go")
-	if _, err := os.Stat(mainGoPath); os.IsNotExist(err) {
-		fmt.Println("Error: Not in a Base Framework project directory")
-		fmt.Println("Please run this command from the root of your Base Framework project")
-		return
-	}
-
 	// Determine module path - try multiple variations
 	var modulePath string
 
@@ -171,6 +167,13 @@ func generateTask(cmd *cobra.Command, args []string) {
 	actualPackageName := filepath.Base(modulePath)
 	taskContent := generateTaskContent(actualPackageName, taskName)
 
+	// Record the new file for base undo
+	recorder := utils.NewJournalRecorder(commandLine())
+	if relPath, err := filepath.Rel(cwd, taskFilePath); err == nil {
+		recorder.Track(relPath)
+	}
+	defer recordJournal(recorder)
+
 	// Write task file
 	err = os.WriteFile(taskFilePath, []byte(taskContent), 0644)
 	if err != nil {
@@ -198,6 +201,7 @@ func generateTaskContent(moduleName, taskName string) string {
 	taskStructName := utils.ToPascalCase(taskName) + "Task"
 	_ = utils.ToCamelCase(taskName) // Reserved for future use
 	taskID := utils.ToKebabCase(taskName)
+	modulePath := utils.ModulePath()
 
 	return fmt.Sprintf(`package %s
 
@@ -205,8 +209,8 @@ import (
 	"context"
 	"time"
 
-	"base/core/logger"
-	"base/core/scheduler"
+	"%s/core/logger"
+	"%s/core/scheduler"
 )
 
 // %s handles %s
@@ -284,6 +288,7 @@ func (t *%s) GetTaskInfo() map[string]any {
 }
 `,
 		packageName,
+		modulePath, modulePath,
 		taskStructName, taskName, taskStructName,
 		taskStructName, taskStructName, taskStructName, taskStructName, taskStructName,
 		taskName, taskStructName,
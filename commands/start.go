@@ -28,6 +28,10 @@ func init() {
 }
 
 func startApplication(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
 	// Get the current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/base-go/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	overwriteTemplates bool
+)
+
+var templatesCmd = &cobra.Command{
+	Use:     "templates",
+	Aliases: []string{"tpl"},
+	Short:   "Manage generator templates",
+	Long: `Manage the templates used by base generate.
+
+The generator looks for templates in .base/templates first and falls back to the
+templates built into the CLI, so any template can be customised per project.
+
+Extra templates can be added by listing them in .base/templates/manifest.yaml,
+each with the path of the file it generates:
+
+  templates:
+    - template: repository.tmpl
+      output: app/{{.DirName}}/repository.go`,
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List templates and where they are loaded from",
+	Args:    cobra.NoArgs,
+	Run:     listTemplates,
+}
+
+var templatesEjectCmd = &cobra.Command{
+	Use:   "eject [template...]",
+	Short: "Copy the built-in templates into .base/templates for customisation",
+	Long: `Copy the built-in templates into .base/templates so they can be customised.
+Without arguments every template is ejected.
+
+Examples:
+  base templates eject
+  base templates eject controller.tmpl
+  base templates eject controller --force`,
+	Run: ejectTemplates,
+}
+
+func init() {
+	templatesEjectCmd.Flags().BoolVarP(&overwriteTemplates, "force", "f", false, "Overwrite templates that were already ejected")
+
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesEjectCmd)
+	rootCmd.AddCommand(templatesCmd)
+}
+
+func listTemplates(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	for _, name := range utils.EmbeddedTemplateNames() {
+		_, source, err := utils.LoadTemplate(name)
+		if err != nil {
+			fmt.Printf("  %-18s ❌ %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  %-18s %s\n", name, source)
+	}
+
+	manifest, err := utils.LoadTemplateManifest()
+	if err != nil {
+		fmt.Printf("\n❌ %v\n", err)
+		return
+	}
+	if len(manifest.Templates) == 0 {
+		return
+	}
+
+	fmt.Printf("\nExtra templates from %s:\n", utils.TemplateManifestPath())
+	for _, entry := range manifest.Templates {
+		fmt.Printf("  %-18s → %s\n", entry.Template, entry.Output)
+	}
+}
+
+func ejectTemplates(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
+	names := utils.EmbeddedTemplateNames()
+	if len(args) > 0 {
+		names = nil
+		for _, arg := range args {
+			if filepath.Ext(arg) == "" {
+				arg += ".tmpl"
+			}
+			names = append(names, arg)
+		}
+	}
+
+	for _, name := range names {
+		target, err := utils.EjectTemplate(name, overwriteTemplates)
+		if err != nil {
+			fmt.Printf("  ⚠️  Skipped %s: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  ✅ Ejected %s\n", target)
+	}
+
+	fmt.Printf("\nTemplates in %s now take precedence over the built-in ones.\n", utils.TemplateOverrideDir)
+}
@@ -25,6 +25,10 @@ func init() {
 }
 
 func updateBaseCore(cmd *cobra.Command, args []string) {
+	if !enterProjectRoot() {
+		return
+	}
+
 	fmt.Println("Updating Base Core...")
 	err := updateCore()
 	if err != nil {
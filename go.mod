@@ -5,12 +5,16 @@ go 1.24
 require (
 	github.com/gertd/go-pluralize v0.2.1
 	github.com/spf13/cobra v1.9.1
+	golang.org/x/mod v0.27.0
 	golang.org/x/text v0.28.0
+	golang.org/x/tools v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.7 // indirect
+	golang.org/x/sync v0.16.0 // indirect
 )
 
 replace github.com/base-go/cmd => ./
@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// AppInitPath is the file that registers the app modules
+var AppInitPath = filepath.Join("app", "init.go")
+
+// appImportPrefix returns the import path prefix of the project's app module packages
+func appImportPrefix() string {
+	return ModulePath() + "/app/"
+}
+
+// defaultAppInit is the app/init.go written when a project does not have one yet. %s is
+// replaced with the module path.
+const defaultAppInit = `package app
+
+import (
+	"%s/core/module"
+)
+
+// AppModules implements module.AppModuleProvider interface
+type AppModules struct{}
+
+// GetAppModules returns the list of app modules to initialize
+// This is the only function that needs to be updated when adding new app modules
+func (am *AppModules) GetAppModules(deps module.Dependencies) map[string]module.Module {
+	modules := make(map[string]module.Module)
+
+	return modules
+}
+
+// NewAppModules creates a new AppModules provider
+func NewAppModules() *AppModules {
+	return &AppModules{}
+}
+`
+
+// moduleCommentPattern matches the comment written above each registration, e.g. "// Posts module"
+var moduleCommentPattern = regexp.MustCompile(`^//\s*(\S+) module$`)
+
+// appRegistration is a modules["name"] = pkg.Init(deps) statement in GetAppModules
+type appRegistration struct {
+	stmt *ast.AssignStmt
+	key  string // Map key the module is registered under
+	pkg  string // Package the module is initialized from, empty when not a pkg.Func(...) call
+	init bool   // The call is pkg.Init(...)
+	deps bool   // The call passes the dependencies parameter and nothing else
+}
+
+// appInit is a parsed app/init.go
+type appInit struct {
+	*goSource
+	fn            *ast.FuncDecl
+	mapName       string
+	depsName      string
+	registrations []appRegistration
+}
+
+func parseAppInit(src []byte) (*appInit, error) {
+	source, err := parseGoSource(AppInitPath, src)
+	if err != nil {
+		return nil, err
+	}
+
+	fn := findMethod(source.file, "AppModules", "GetAppModules")
+	if fn == nil {
+		fn = findMethod(source.file, "", "GetAppModules")
+	}
+	if fn == nil || fn.Body == nil {
+		return nil, fmt.Errorf("GetAppModules not found in %s", AppInitPath)
+	}
+
+	a := &appInit{goSource: source, fn: fn, mapName: "modules", depsName: "deps"}
+	if params := fn.Type.Params.List; len(params) > 0 && len(params[0].Names) > 0 {
+		a.depsName = params[0].Names[0].Name
+	}
+	if ret := a.returnStmt(); ret != nil && len(ret.Results) == 1 {
+		if ident, ok := ret.Results[0].(*ast.Ident); ok {
+			a.mapName = ident.Name
+		} else {
+			// e.g. a map literal, which registrations cannot be added to
+			a.mapName = ""
+		}
+	}
+
+	for _, stmt := range fn.Body.List {
+		if registration, ok := a.registration(stmt); ok {
+			a.registrations = append(a.registrations, registration)
+		}
+	}
+	return a, nil
+}
+
+// returnStmt returns the final return statement of GetAppModules, or nil
+func (a *appInit) returnStmt() *ast.ReturnStmt {
+	list := a.fn.Body.List
+	if len(list) == 0 {
+		return nil
+	}
+	ret, _ := list[len(list)-1].(*ast.ReturnStmt)
+	return ret
+}
+
+// registration reports whether stmt assigns a module to the modules map
+func (a *appInit) registration(stmt ast.Stmt) (appRegistration, bool) {
+	assign, ok := stmt.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.ASSIGN || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return appRegistration{}, false
+	}
+	index, ok := assign.Lhs[0].(*ast.IndexExpr)
+	if !ok {
+		return appRegistration{}, false
+	}
+	if ident, ok := index.X.(*ast.Ident); !ok || ident.Name != a.mapName {
+		return appRegistration{}, false
+	}
+	lit, ok := index.Index.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return appRegistration{}, false
+	}
+	key, _ := strconv.Unquote(lit.Value)
+
+	registration := appRegistration{stmt: assign, key: key}
+	if call, ok := assign.Rhs[0].(*ast.CallExpr); ok {
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				registration.pkg = pkg.Name
+				registration.init = sel.Sel.Name == "Init"
+			}
+		}
+		if len(call.Args) == 1 {
+			if arg, ok := call.Args[0].(*ast.Ident); ok && arg.Name == a.depsName {
+				registration.deps = true
+			}
+		}
+	}
+	return registration, true
+}
+
+// find returns the registrations under key
+func (a *appInit) find(key string) []appRegistration {
+	var found []appRegistration
+	for _, registration := range a.registrations {
+		if registration.key == key {
+			found = append(found, registration)
+		}
+	}
+	return found
+}
+
+// moduleComment returns the comment written above a module's registration
+func moduleComment(module string) string {
+	return "// " + ToTitle(module) + " module"
+}
+
+// formatAppInit adds or removes the module import and formats the result
+func formatAppInit(src []byte, module string, register bool) ([]byte, error) {
+	result, err := parseGoSource(AppInitPath, src)
+	if err != nil {
+		return nil, err
+	}
+
+	importPath := appImportPrefix() + module
+	if register {
+		astutil.AddImport(result.fset, result.file, importPath)
+	} else if !astutil.UsesImport(result.file, importPath) {
+		astutil.DeleteImport(result.fset, result.file, importPath)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, result.fset, result.file); err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", AppInitPath, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// RegisterAppModule adds the import and the modules["name"] = name.Init(deps) registration of
+// a module to app/init.go. A nil src yields a fresh file. Registering a module twice is a no-op.
+func RegisterAppModule(src []byte, module string) ([]byte, error) {
+	if src == nil {
+		src = []byte(fmt.Sprintf(defaultAppInit, ModulePath()))
+	}
+	a, err := parseAppInit(src)
+	if err != nil {
+		return nil, err
+	}
+
+	// An existing registration is left as it is, AppInitDrift reports it if it looks wrong.
+	// Only a missing import is added back.
+	if existing := a.find(module); len(existing) > 0 {
+		for _, registration := range existing {
+			if registration.pkg == module && !astutil.UsesImport(a.file, appImportPrefix()+module) {
+				return formatAppInit(src, module, true)
+			}
+		}
+		return src, nil
+	}
+
+	if a.mapName == "" {
+		return nil, fmt.Errorf("GetAppModules does not return a modules variable")
+	}
+
+	text := fmt.Sprintf("%s\n%s[%q] = %s.Init(%s)", moduleComment(module), a.mapName, module, module, a.depsName)
+
+	// Append after the last registration, or else after the statement before the return
+	list := a.fn.Body.List
+	ret := a.returnStmt()
+	var after ast.Stmt
+	switch {
+	case len(a.registrations) > 0:
+		after = a.registrations[len(a.registrations)-1].stmt
+	case ret != nil && len(list) > 1:
+		after = list[len(list)-2]
+	case ret == nil && len(list) > 0:
+		after = list[len(list)-1]
+	}
+
+	var edit sourceEdit
+	switch {
+	case after != nil:
+		// Insert at the end of its line so a trailing comment stays in place
+		end := a.offset(after.End())
+		if i := bytes.IndexByte(a.src[end:], '\n'); i >= 0 {
+			end += i
+		} else {
+			end = len(a.src)
+		}
+		edit = sourceEdit{start: end, end: end, text: "\n\n" + text}
+	case ret != nil:
+		edit = a.insertBefore(ret.Pos(), text+"\n")
+	default:
+		edit = a.insertBefore(a.fn.Body.Rbrace, text)
+	}
+
+	return formatAppInit(applyEdits(a.src, []sourceEdit{edit}), module, true)
+}
+
+// UnregisterAppModule removes a module's registration, the comment above it and its import
+// from app/init.go
+func UnregisterAppModule(src []byte, module string) ([]byte, error) {
+	a, err := parseAppInit(src)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := moduleComment(module)
+	var edits []sourceEdit
+	for _, registration := range a.registrations {
+		if registration.key != module && registration.pkg != module {
+			continue
+		}
+		edits = append(edits, a.deleteNode(registration.stmt, a.commentAbove(registration.stmt) == comment))
+	}
+
+	// Comments left behind by earlier hand edits
+	for _, group := range a.file.Comments {
+		if group.Pos() < a.fn.Body.Lbrace || group.End() > a.fn.Body.Rbrace {
+			continue
+		}
+		if strings.TrimSpace(group.Text()) == strings.TrimPrefix(comment, "// ") && len(group.List) == 1 {
+			edits = append(edits, a.deleteNode(group, false))
+		}
+	}
+
+	edits = dropNestedEdits(edits)
+	return formatAppInit(applyEdits(a.src, edits), module, false)
+}
+
+// commentAbove returns the line directly above node when it is a line comment
+func (a *appInit) commentAbove(node ast.Node) string {
+	lineStart := bytes.LastIndexByte(a.src[:a.offset(node.Pos())], '\n') + 1
+	if lineStart == 0 {
+		return ""
+	}
+	prevStart := bytes.LastIndexByte(a.src[:lineStart-1], '\n') + 1
+	line := strings.TrimSpace(string(a.src[prevStart:lineStart]))
+	if !strings.HasPrefix(line, "//") {
+		return ""
+	}
+	return line
+}
+
+// AppInitDrift lists the places where app/init.go no longer matches what the generator writes:
+// registrations that are not name.Init(deps), duplicates, missing or unused imports, module
+// comments without a registration and a GetAppModules that does not return a modules variable
+func AppInitDrift(src []byte) ([]string, error) {
+	a, err := parseAppInit(src)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := appImportPrefix()
+	imported := make(map[string]bool)
+	for _, imp := range a.file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		if strings.HasPrefix(importPath, prefix) {
+			imported[strings.TrimPrefix(importPath, prefix)] = true
+		}
+	}
+
+	// Problems are collected with their line so they can be reported in file order
+	type problem struct {
+		line    int
+		message string
+	}
+	var problems []problem
+	line := func(node ast.Node) int { return a.fset.Position(node.Pos()).Line }
+	if a.mapName == "" {
+		problems = append(problems, problem{line(a.returnStmt()), "GetAppModules does not return a modules variable, so modules cannot be registered"})
+	}
+	registered := make(map[string]bool)
+	seen := make(map[string]bool)
+	for _, registration := range a.registrations {
+		stmt := a.text(registration.stmt)
+		switch {
+		case seen[registration.key]:
+			problems = append(problems, problem{line(registration.stmt), fmt.Sprintf("%q is registered more than once", registration.key)})
+		case !registration.init || !registration.deps:
+			problems = append(problems, problem{line(registration.stmt), fmt.Sprintf("%s does not call %s.Init(%s)", stmt, registration.key, a.depsName)})
+		case registration.pkg != registration.key:
+			problems = append(problems, problem{line(registration.stmt), fmt.Sprintf("%s registers package %s under a different name", stmt, registration.pkg)})
+		case !imported[registration.pkg]:
+			problems = append(problems, problem{line(registration.stmt), fmt.Sprintf("%s uses package %s, which is not imported from %s%s", stmt, registration.pkg, prefix, registration.pkg)})
+		}
+		seen[registration.key] = true
+		if registration.pkg != "" {
+			registered[registration.pkg] = true
+		}
+	}
+
+	for _, imp := range a.file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		module := strings.TrimPrefix(importPath, prefix)
+		// A map literal's entries are not registrations, the problem is reported above
+		if strings.HasPrefix(importPath, prefix) && !registered[module] && a.mapName != "" {
+			problems = append(problems, problem{line(imp), fmt.Sprintf("%s is imported but not registered", importPath)})
+		}
+	}
+
+	for _, group := range a.file.Comments {
+		if group.Pos() < a.fn.Body.Lbrace || group.End() > a.fn.Body.Rbrace || len(group.List) != 1 {
+			continue
+		}
+		if !moduleCommentPattern.MatchString(group.List[0].Text) {
+			continue
+		}
+		orphaned := true
+		for _, registration := range a.registrations {
+			if a.commentAbove(registration.stmt) == group.List[0].Text && line(registration.stmt) == line(group)+1 {
+				orphaned = false
+				break
+			}
+		}
+		if orphaned {
+			problems = append(problems, problem{line(group), fmt.Sprintf("comment %q is not followed by a module registration", group.List[0].Text)})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].line < problems[j].line })
+	var drift []string
+	for _, p := range problems {
+		drift = append(drift, fmt.Sprintf("line %d: %s", p.line, p.message))
+	}
+	return drift, nil
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// appInitWith returns the default app/init.go with the given imports, sorted as gofmt does,
+// and GetAppModules body
+func appInitWith(imports []string, body string) string {
+	imports = append([]string{"base/core/module"}, imports...)
+	slices.Sort(imports)
+	var lines []string
+	for _, imp := range imports {
+		lines = append(lines, fmt.Sprintf("\t%q", imp))
+	}
+	return fmt.Sprintf(`package app
+
+import (
+%s
+)
+
+// AppModules implements module.AppModuleProvider interface
+type AppModules struct{}
+
+// GetAppModules returns the list of app modules to initialize
+// This is the only function that needs to be updated when adding new app modules
+func (am *AppModules) GetAppModules(deps module.Dependencies) map[string]module.Module {
+%s
+}
+
+// NewAppModules creates a new AppModules provider
+func NewAppModules() *AppModules {
+	return &AppModules{}
+}
+`, strings.Join(lines, "\n"), body)
+}
+
+const (
+	emptyModules = "\tmodules := make(map[string]module.Module)\n\n\treturn modules"
+	postsModules = "\tmodules := make(map[string]module.Module)\n\n\t// Posts module\n\tmodules[\"posts\"] = posts.Init(deps)\n\n\treturn modules"
+	bothModules  = "\tmodules := make(map[string]module.Module)\n\n\t// Posts module\n\tmodules[\"posts\"] = posts.Init(deps)\n\n\t// Users module\n\tmodules[\"users\"] = users.Init(deps)\n\n\treturn modules"
+)
+
+func TestRegisterAppModule(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		module string
+		want   string
+	}{
+		{
+			name:   "first module",
+			src:    appInitWith(nil, emptyModules),
+			module: "posts",
+			want:   appInitWith([]string{"base/app/posts"}, postsModules),
+		},
+		{
+			name:   "after existing modules",
+			src:    appInitWith([]string{"base/app/posts"}, postsModules),
+			module: "users",
+			want:   appInitWith([]string{"base/app/posts", "base/app/users"}, bothModules),
+		},
+		{
+			name:   "duplicate",
+			src:    appInitWith([]string{"base/app/posts"}, postsModules),
+			module: "posts",
+			want:   appInitWith([]string{"base/app/posts"}, postsModules),
+		},
+		{
+			name:   "duplicate without its import",
+			src:    appInitWith(nil, postsModules),
+			module: "posts",
+			want:   appInitWith([]string{"base/app/posts"}, postsModules),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RegisterAppModule([]byte(tt.src), tt.module)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := UnifiedDiff("want", "got", []byte(tt.want), got); diff != "" {
+				t.Errorf("unexpected app/init.go:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUnregisterAppModule(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		module string
+		want   string
+	}{
+		{
+			name:   "last module",
+			src:    appInitWith([]string{"base/app/posts"}, postsModules),
+			module: "posts",
+			want:   appInitWith(nil, emptyModules),
+		},
+		{
+			name:   "one of two modules",
+			src:    appInitWith([]string{"base/app/posts", "base/app/users"}, bothModules),
+			module: "users",
+			want:   appInitWith([]string{"base/app/posts"}, postsModules),
+		},
+		{
+			name:   "module that is not registered",
+			src:    appInitWith([]string{"base/app/posts"}, postsModules),
+			module: "users",
+			want:   appInitWith([]string{"base/app/posts"}, postsModules),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnregisterAppModule([]byte(tt.src), tt.module)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := UnifiedDiff("want", "got", []byte(tt.want), got); diff != "" {
+				t.Errorf("unexpected app/init.go:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAppInitDrift(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "generated layout",
+			src:  appInitWith([]string{"base/app/posts", "base/app/users"}, bothModules),
+		},
+		{
+			name: "hand-written registration",
+			src: appInitWith([]string{"base/app/posts"},
+				"\tmodules := make(map[string]module.Module)\n\n\t// Posts module\n\tmodules[\"posts\"] = posts.NewModule(deps.DB)\n\n\treturn modules"),
+			want: []string{`line 17: modules["posts"] = posts.NewModule(deps.DB) does not call posts.Init(deps)`},
+		},
+		{
+			name: "duplicate and orphaned comment",
+			src: appInitWith([]string{"base/app/posts"},
+				"\tmodules := make(map[string]module.Module)\n\n\t// Users module\n\n\tmodules[\"posts\"] = posts.Init(deps)\n\tmodules[\"posts\"] = posts.Init(deps)\n\n\treturn modules"),
+			want: []string{
+				`line 16: comment "// Users module" is not followed by a module registration`,
+				`line 19: "posts" is registered more than once`,
+			},
+		},
+		{
+			name: "unused and missing imports",
+			src: appInitWith([]string{"base/app/users"},
+				"\tmodules := make(map[string]module.Module)\n\n\t// Posts module\n\tmodules[\"posts\"] = posts.Init(deps)\n\n\treturn modules"),
+			want: []string{
+				"line 4: base/app/users is imported but not registered",
+				`line 17: modules["posts"] = posts.Init(deps) uses package posts, which is not imported from base/app/posts`,
+			},
+		},
+		{
+			name: "map literal",
+			src: appInitWith([]string{"base/app/posts"},
+				"\treturn map[string]module.Module{\n\t\t\"posts\": posts.Init(deps),\n\t}"),
+			want: []string{"line 14: GetAppModules does not return a modules variable, so modules cannot be registered"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift, err := AppInitDrift([]byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(drift, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got drift:\n%s\nwant:\n%s", strings.Join(drift, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+// A hand-written app/init.go is reported and left alone
+func TestRegisterAppModuleLeavesHandWrittenInit(t *testing.T) {
+	handWritten := appInitWith([]string{"base/app/posts"},
+		"\tmodules := make(map[string]module.Module)\n\n\t// Posts module\n\tmodules[\"posts\"] = posts.NewModule(deps.DB)\n\n\treturn modules")
+	got, err := RegisterAppModule([]byte(handWritten), "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != handWritten {
+		t.Errorf("the registration was edited:\n%s", UnifiedDiff("want", "got", []byte(handWritten), got))
+	}
+
+	literal := appInitWith([]string{"base/app/posts"}, "\treturn map[string]module.Module{\n\t\t\"posts\": posts.Init(deps),\n\t}")
+	if got, err := RegisterAppModule([]byte(literal), "users"); err == nil {
+		t.Errorf("expected an error for a map literal, got:\n%s", got)
+	}
+
+	if _, err := RegisterAppModule([]byte("package app\n"), "users"); err == nil {
+		t.Error("expected an error without GetAppModules")
+	}
+}
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FileChange is a pending write produced by the generator
+type FileChange struct {
+	Path   string
+	Before []byte // Current content on disk, nil when the file does not exist
+	After  []byte // Content that will be written
+	Edit   bool   // The change edits an existing file in place (e.g. app/init.go) rather than regenerating it
+
+	// Template and Fields record what a generated file was rendered from, so problems in it
+	// can be traced back. Both are empty for edits.
+	Template string
+	Fields   []Field
+}
+
+// IsConflict reports whether the change would overwrite a regenerated file that differs on disk
+func (c *FileChange) IsConflict() bool {
+	return !c.Edit && !c.IsNew() && !c.IsUnchanged()
+}
+
+// IsNew reports whether the file does not exist yet
+func (c *FileChange) IsNew() bool {
+	return c.Before == nil
+}
+
+// IsUnchanged reports whether writing the change would leave the file as it is
+func (c *FileChange) IsUnchanged() bool {
+	return c.Before != nil && bytes.Equal(c.Before, c.After)
+}
+
+// Status returns a short label describing the change
+func (c *FileChange) Status() string {
+	switch {
+	case c.IsNew():
+		return "create"
+	case c.IsUnchanged():
+		return "unchanged"
+	default:
+		return "update"
+	}
+}
+
+// Diff returns a unified diff of the change
+func (c *FileChange) Diff() string {
+	oldName := "a/" + filepath.ToSlash(c.Path)
+	if c.IsNew() {
+		oldName = "/dev/null"
+	}
+	return UnifiedDiff(oldName, "b/"+filepath.ToSlash(c.Path), c.Before, c.After)
+}
+
+// ChangeSet collects file writes so they can be previewed before anything touches the disk
+type ChangeSet struct {
+	Changes []*FileChange
+}
+
+// NewChangeSet creates an empty change set
+func NewChangeSet() *ChangeSet {
+	return &ChangeSet{}
+}
+
+// Get returns the planned change for path, or nil
+func (cs *ChangeSet) Get(path string) *FileChange {
+	path = filepath.Clean(path)
+	for _, change := range cs.Changes {
+		if change.Path == path {
+			return change
+		}
+	}
+	return nil
+}
+
+// Current returns the content path will have once the change set is applied,
+// and whether the file exists at all
+func (cs *ChangeSet) Current(path string) ([]byte, bool, error) {
+	if change := cs.Get(path); change != nil {
+		return change.After, true, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+// Add plans writing content to path. Adding the same path twice replaces the planned content.
+func (cs *ChangeSet) Add(path string, content []byte) (*FileChange, error) {
+	path = filepath.Clean(path)
+	if change := cs.Get(path); change != nil {
+		change.After = content
+		return change, nil
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if os.IsNotExist(err) {
+		before = nil
+	}
+
+	change := &FileChange{Path: path, Before: before, After: content}
+	cs.Changes = append(cs.Changes, change)
+	return change, nil
+}
+
+// AddEdit plans an in-place edit of an existing file, which is never treated as a conflict
+func (cs *ChangeSet) AddEdit(path string, content []byte) (*FileChange, error) {
+	change, err := cs.Add(path, content)
+	if err != nil {
+		return nil, err
+	}
+	change.Edit = true
+	return change, nil
+}
+
+// Remove drops a planned change so the file is left untouched
+func (cs *ChangeSet) Remove(change *FileChange) {
+	for i, c := range cs.Changes {
+		if c == change {
+			cs.Changes = append(cs.Changes[:i], cs.Changes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Redirect writes a planned change to a different path instead (e.g. "model.go.new")
+func (cs *ChangeSet) Redirect(change *FileChange, path string) error {
+	before, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if os.IsNotExist(err) {
+		before = nil
+	}
+
+	change.Path = filepath.Clean(path)
+	change.Before = before
+	change.Edit = false
+	return nil
+}
+
+// Conflicts returns the changes that would overwrite existing files with different content
+func (cs *ChangeSet) Conflicts() []*FileChange {
+	var conflicts []*FileChange
+	for _, change := range cs.Changes {
+		if change.IsConflict() {
+			conflicts = append(conflicts, change)
+		}
+	}
+	return conflicts
+}
+
+// Apply writes every changed file to disk
+func (cs *ChangeSet) Apply() error {
+	for _, change := range cs.Changes {
+		if change.IsUnchanged() {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(change.Path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", change.Path, err)
+		}
+		if err := os.WriteFile(change.Path, change.After, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", change.Path, err)
+		}
+	}
+	return nil
+}
+
+// Rollback restores every changed file to its content before Apply. Files that did not
+// exist are removed, together with any directories left empty.
+func (cs *ChangeSet) Rollback() error {
+	for _, change := range cs.Changes {
+		if change.IsUnchanged() {
+			continue
+		}
+		if !change.IsNew() {
+			if err := os.WriteFile(change.Path, change.Before, 0644); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", change.Path, err)
+			}
+			continue
+		}
+		if err := os.Remove(change.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", change.Path, err)
+		}
+		removeEmptyDirs(filepath.Dir(change.Path))
+	}
+	return nil
+}
+
+// Print writes a summary of the planned changes, followed by their diffs when showDiff is set
+func (cs *ChangeSet) Print(w io.Writer, showDiff bool) {
+	for _, change := range cs.Changes {
+		fmt.Fprintf(w, "  %-9s %s\n", change.Status(), change.Path)
+	}
+
+	if !showDiff {
+		return
+	}
+
+	for _, change := range cs.Changes {
+		if diff := change.Diff(); diff != "" {
+			fmt.Fprintln(w)
+			fmt.Fprint(w, diff)
+		}
+	}
+}
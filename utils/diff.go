@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is a single line-level edit operation
+type diffOp struct {
+	kind byte // ' ' keep, '-' delete, '+' insert
+	line string
+}
+
+// splitLines splits content into lines without their trailing newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a line-level edit script from a to b using the longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// UnifiedDiff returns a unified diff between two versions of a file, or "" when they are equal
+func UnifiedDiff(oldName, newName string, oldContent, newContent []byte) string {
+	ops := diffLines(splitLines(string(oldContent)), splitLines(string(newContent)))
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Walk the edit script and emit hunks with surrounding context
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		hunkStart := max(first-diffContext, 0)
+		end := first
+		for last := first; last < len(ops); last++ {
+			if ops[last].kind != ' ' {
+				end = last
+			} else if last-end > 2*diffContext {
+				break
+			}
+		}
+		hunkEnd := min(end+diffContext+1, len(ops))
+
+		// Compute line numbers for the hunk header
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		if oldCount == 0 {
+			oldLine--
+		}
+		if newCount == 0 {
+			newLine--
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+
+		start = hunkEnd
+	}
+
+	return out.String()
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\nb", []string{"a", "b"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.content); !slices.Equal(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string // One op per line: the kind followed by the line
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: " a\n b\n",
+		},
+		{
+			name: "change",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: " a\n-b\n+B\n c\n",
+		},
+		{
+			name: "insertions at both ends",
+			a:    "b\n",
+			b:    "a\nb\nc\n",
+			want: "+a\n b\n+c\n",
+		},
+		{
+			name: "adjacent insertions",
+			a:    "a\nd\n",
+			b:    "a\nb\nc\nd\n",
+			want: " a\n+b\n+c\n d\n",
+		},
+		{
+			name: "deletion",
+			a:    "a\nb\nc\n",
+			b:    "a\nc\n",
+			want: " a\n-b\n c\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\n",
+			want: "+a\n",
+		},
+		{
+			name: "to empty",
+			a:    "a\n",
+			b:    "",
+			want: "-a\n",
+		},
+		{
+			name: "no trailing newline",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: " a\n b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			for _, op := range diffLines(splitLines(tt.a), splitLines(tt.b)) {
+				got.WriteByte(op.kind)
+				got.WriteString(op.line + "\n")
+			}
+			if got.String() != tt.want {
+				t.Errorf("diffLines:\n%s\nwant:\n%s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("a", "b", []byte("x\n"), []byte("x\n")); got != "" {
+		t.Errorf("equal content: got %q, want no diff", got)
+	}
+
+	got := UnifiedDiff("old.go", "new.go", []byte("a\nb\nc\n"), []byte("a\nB\nc\n"))
+	want := "--- old.go\n+++ new.go\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -7,7 +7,7 @@ type FieldTypeAlias struct {
 	Alias         string // User input (e.g., "image", "belongsTo", "manyToMany")
 	CanonicalType string // Standardized type (e.g., "storage.Attachment", "belongs_to", "many_to_many")
 	GoType        string // Go type for struct fields
-	Category      string // "storage", "relationship", "basic", "translation"
+	Category      string // "storage", "relationship", "basic", "translation", "secret", "format"
 }
 
 // FieldTypeAliases defines all supported field type aliases
@@ -26,10 +26,16 @@ var FieldTypeAliases = []FieldTypeAlias{
 
 	// Basic types with aliases
 	{"text", "string", "string", "basic"},
-	{"email", "string", "string", "basic"},
-	{"password", "string", "string", "basic"},
-	{"url", "string", "string", "basic"},
-	{"phone", "string", "string", "basic"},
+
+	// Strings with a format, checked by the generated validators
+	{"email", "email", "string", "format"},
+	{"url", "url", "string", "format"},
+	{"phone", "phone", "string", "format"},
+	{"slug", "slug", "string", "format"},
+
+	// Secret types, stored as bcrypt hashes
+	{"password", "string", "string", "secret"},
+	{"secret", "string", "string", "secret"},
 
 	// Relationship types - GORM standard names
 	{"belongsTo", "belongs_to", "", "relationship"},
@@ -42,6 +48,8 @@ var FieldTypeAliases = []FieldTypeAlias{
 	{"many_to_many", "many_to_many", "", "relationship"},
 	{"toMany", "many_to_many", "", "relationship"},
 	{"to_many", "many_to_many", "", "relationship"},
+	{"tree", "belongs_to", "", "relationship"}, // parent:tree is parent:belongsTo:self
+	{"polymorphic", "polymorphic", "", "relationship"},
 
 	// Date/time aliases
 	{"datetime", "types.DateTime", "types.DateTime", "basic"},
@@ -0,0 +1,1598 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// sourceEdit replaces src[start:end] with text. Insertions use start == end.
+type sourceEdit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits applies non-overlapping edits to src
+func applyEdits(src []byte, edits []sourceEdit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte{}, src...)
+	for _, edit := range edits {
+		out = append(out[:edit.start], append([]byte(edit.text), out[edit.end:]...)...)
+	}
+	return out
+}
+
+// goSource is a parsed Go file together with its source
+type goSource struct {
+	src  []byte
+	fset *token.FileSet
+	file *ast.File
+}
+
+func parseGoSource(filename string, src []byte) (*goSource, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+	return &goSource{src: src, fset: fset, file: file}, nil
+}
+
+func (s *goSource) offset(pos token.Pos) int {
+	return s.fset.Position(pos).Offset
+}
+
+func (s *goSource) text(node ast.Node) string {
+	return string(s.src[s.offset(node.Pos()):s.offset(node.End())])
+}
+
+// insertBefore returns an edit inserting text on its own line(s) just before the line holding pos
+func (s *goSource) insertBefore(pos token.Pos, text string) sourceEdit {
+	offset := s.offset(pos)
+	lineStart := bytes.LastIndexByte(s.src[:offset], '\n') + 1
+	if strings.TrimSpace(string(s.src[lineStart:offset])) != "" {
+		// Something precedes pos on its line (e.g. "{}"), break the line instead
+		return sourceEdit{start: offset, end: offset, text: "\n" + text + "\n"}
+	}
+	return sourceEdit{start: lineStart, end: lineStart, text: text + "\n"}
+}
+
+// findStruct returns the struct type declared with the given name
+func findStruct(file *ast.File, name string) *ast.StructType {
+	var found *ast.StructType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok && spec.Name.Name == name {
+			found, _ = spec.Type.(*ast.StructType)
+			return false
+		}
+		return found == nil
+	})
+	return found
+}
+
+// findMethod returns the function or method with the given name and receiver type ("" for functions)
+func findMethod(file *ast.File, recv, name string) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != name || fn.Body == nil {
+			continue
+		}
+		if recv == "" && fn.Recv == nil {
+			return fn
+		}
+		if recv != "" && fn.Recv != nil && len(fn.Recv.List) == 1 && receiverName(fn.Recv.List[0].Type) == recv {
+			return fn
+		}
+	}
+	return nil
+}
+
+func receiverName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// findCompositeLit returns the first composite literal of the named type inside node.
+// typeName matches both "Post" and "models.Post".
+func findCompositeLit(node ast.Node, typeName string) *ast.CompositeLit {
+	var found *ast.CompositeLit
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		switch t := lit.Type.(type) {
+		case *ast.Ident:
+			if t.Name == typeName {
+				found = lit
+			}
+		case *ast.SelectorExpr:
+			if t.Sel.Name == typeName {
+				found = lit
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// findAssignedLit returns the composite literal assigned to the named variable inside node
+func findAssignedLit(node ast.Node, variable string) *ast.CompositeLit {
+	var found *ast.CompositeLit
+	ast.Inspect(node, func(n ast.Node) bool {
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || found != nil {
+			return found == nil
+		}
+		for i, lhs := range assign.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok && ident.Name == variable && i < len(assign.Rhs) {
+				found, _ = assign.Rhs[i].(*ast.CompositeLit)
+			}
+		}
+		return found == nil
+	})
+	return found
+}
+
+// findStmt returns the index of the first statement in body whose source contains marker
+func (s *goSource) findStmt(body *ast.BlockStmt, marker string) int {
+	for i, stmt := range body.List {
+		if strings.Contains(s.text(stmt), marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// structFieldNames returns the names declared in a struct
+func structFieldNames(st *ast.StructType) map[string]bool {
+	names := make(map[string]bool)
+	for _, field := range st.Fields.List {
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
+
+// litKey returns the key of a composite literal element as written, e.g. Title or "title"
+func litKey(elt ast.Expr) string {
+	kv, ok := elt.(*ast.KeyValueExpr)
+	if !ok {
+		return ""
+	}
+	switch key := kv.Key.(type) {
+	case *ast.Ident:
+		return key.Name
+	case *ast.BasicLit:
+		if value, err := strconv.Unquote(key.Value); err == nil {
+			return value
+		}
+		return key.Value
+	}
+	return ""
+}
+
+// fieldInserter collects the edits needed to add the snippets rendered for new fields to an existing file
+type fieldInserter struct {
+	target   *goSource
+	rendered *goSource
+	edits    []sourceEdit
+}
+
+// structFields copies the fields of the rendered struct that the existing struct lacks
+func (fi *fieldInserter) structFields(name string) error {
+	existing := findStruct(fi.target.file, name)
+	if existing == nil {
+		return fmt.Errorf("struct %s not found", name)
+	}
+	rendered := findStruct(fi.rendered.file, name)
+	if rendered == nil {
+		return nil
+	}
+
+	names := structFieldNames(existing)
+	var lines []string
+	for _, field := range rendered.Fields.List {
+		if len(field.Names) == 0 || names[field.Names[0].Name] {
+			continue
+		}
+		lines = append(lines, "\t"+fi.rendered.text(field))
+	}
+	if len(lines) > 0 {
+		fi.edits = append(fi.edits, fi.target.insertBefore(existing.Fields.Closing, strings.Join(lines, "\n")))
+	}
+	return nil
+}
+
+// litElements copies the elements of a rendered composite literal that the existing literal lacks
+func (fi *fieldInserter) litElements(existing, rendered *ast.CompositeLit) {
+	if existing == nil || rendered == nil {
+		return
+	}
+
+	keys := make(map[string]bool)
+	for _, elt := range existing.Elts {
+		keys[litKey(elt)] = true
+	}
+
+	var lines []string
+	for _, elt := range rendered.Elts {
+		if key := litKey(elt); key != "" && !keys[key] {
+			lines = append(lines, "\t"+fi.rendered.text(elt)+",")
+		}
+	}
+	if len(lines) > 0 {
+		fi.edits = append(fi.edits, fi.target.insertBefore(existing.Rbrace, strings.Join(lines, "\n")))
+	}
+}
+
+// statements copies the rendered statements between the statements matching after and before
+// into the existing function, just before its statement matching before. An empty before
+// copies the statements up to the end of the function.
+func (fi *fieldInserter) statements(recv, funcName, after, before string) {
+	existingFn := findMethod(fi.target.file, recv, funcName)
+	renderedFn := findMethod(fi.rendered.file, recv, funcName)
+	if existingFn == nil || renderedFn == nil {
+		return
+	}
+
+	start := fi.rendered.findStmt(renderedFn.Body, after)
+	end, regionEnd := len(renderedFn.Body.List), renderedFn.Body.Rbrace
+	if before != "" {
+		if end = fi.rendered.findStmt(renderedFn.Body, before); end >= 0 {
+			regionEnd = renderedFn.Body.List[end].Pos()
+		}
+	}
+	if start < 0 || end <= start+1 {
+		return
+	}
+	region := string(fi.rendered.src[fi.rendered.offset(renderedFn.Body.List[start].End()):fi.rendered.offset(regionEnd)])
+
+	anchor := len(existingFn.Body.List)
+	if before != "" {
+		anchor = fi.target.findStmt(existingFn.Body, before)
+	}
+	if anchor < 0 {
+		return
+	}
+
+	// Leading comments the function already has (e.g. "// Update fields directly on the model") are not
+	// repeated. The comment directly above the first statement belongs to it and is kept, so the
+	// result matches what the template renders for the field, as does a blank line before it.
+	existingText := fi.target.text(existingFn.Body)
+	lines := strings.Split(region, "\n")[1:]
+	blank := false
+	for len(lines) > 0 {
+		line := strings.TrimSpace(lines[0])
+		if line != "" && (!strings.HasPrefix(line, "//") || !strings.Contains(existingText, line)) {
+			break
+		}
+		if line != "" && len(lines) > 1 {
+			if next := strings.TrimSpace(lines[1]); next != "" && !strings.HasPrefix(next, "//") {
+				break
+			}
+		}
+		blank = line == ""
+		lines = lines[1:]
+	}
+	// Trailing comments belong to the anchor statement, which already has its own
+	for len(lines) > 0 {
+		if line := strings.TrimSpace(lines[len(lines)-1]); line != "" && !strings.HasPrefix(line, "//") {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return
+	}
+	snippet := strings.Join(lines, "\n")
+
+	if anchor == 0 {
+		fi.edits = append(fi.edits, fi.target.insertBefore(existingFn.Body.List[anchor].Pos(), snippet))
+		return
+	}
+	// Append to the preceding statement so the blank line before the anchor is kept
+	offset := fi.target.offset(existingFn.Body.List[anchor-1].End())
+	if blank {
+		snippet = "\n" + snippet
+	}
+	fi.edits = append(fi.edits, sourceEdit{start: offset, end: offset, text: "\n" + snippet})
+}
+
+// finish applies the edits, adds imports the new code needs and formats the result
+func (fi *fieldInserter) finish(filename string) ([]byte, error) {
+	updated := applyEdits(fi.target.src, fi.edits)
+
+	result, err := parseGoSource(filename, updated)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add imports used by the inserted code, taking their paths from the rendered file
+	available := make(map[string]*ast.ImportSpec)
+	for _, imp := range fi.rendered.file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		available[name] = imp
+	}
+	for _, ident := range result.file.Unresolved {
+		if imp, ok := available[ident.Name]; ok {
+			importPath, _ := strconv.Unquote(imp.Path.Value)
+			// Keep aliases such as gormlogger, or the import is added a second time without one
+			alias := ""
+			if imp.Name != nil {
+				alias = imp.Name.Name
+			}
+			astutil.AddNamedImport(result.fset, result.file, alias, importPath)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, result.fset, result.file); err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// AddModelFields inserts fields into an existing model file: the model, request and response
+// structs, and the ToResponse, ToListResponse and Preload methods. The snippets are taken from
+// model.tmpl rendered with only the new fields, so template overrides are respected.
+func AddModelFields(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	filename := naming.ModelSnake + ".go"
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	model := findStruct(target.file, naming.Model)
+	if model == nil {
+		return nil, fmt.Errorf("struct %s not found in %s", naming.Model, filename)
+	}
+	existing := structFieldNames(model)
+	for _, field := range fields {
+		if existing[field.Name] {
+			return nil, fmt.Errorf("field %s already exists in %s", field.Name, naming.Model)
+		}
+	}
+
+	renderedSrc, err := RenderTemplate("model.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := parseGoSource("model.tmpl", renderedSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fieldInserter{target: target, rendered: rendered}
+	for _, name := range []string{
+		naming.Model,
+		"Create" + naming.Model + "Request",
+		"Update" + naming.Model + "Request",
+		naming.Model + "Response",
+		naming.Model + "ListResponse",
+	} {
+		if err := fi.structFields(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	for _, method := range []struct{ name, literal string }{
+		{"ToResponse", naming.Model + "Response"},
+		{"ToListResponse", naming.Model + "ListResponse"},
+	} {
+		existingFn := findMethod(target.file, naming.Model, method.name)
+		renderedFn := findMethod(rendered.file, naming.Model, method.name)
+		if existingFn == nil || renderedFn == nil {
+			continue
+		}
+		fi.litElements(findCompositeLit(existingFn.Body, method.literal), findCompositeLit(renderedFn.Body, method.literal))
+	}
+
+	// Relationship conversions in ToResponse and preloads
+	fi.statements(naming.Model, "ToResponse", "response :=", "return response")
+	fi.statements(naming.Model, "Preload", "query := db", "return query")
+
+	if err := fi.enumTypes(naming, fields); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return fi.finish(filename)
+}
+
+// AddValidatorFields adds the checks of enum and format fields to the create and update
+// validators of a module's validator.go, with the format helpers they call
+func AddValidatorFields(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	filename := "validator.go"
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSrc, err := RenderTemplate("validator.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := parseGoSource("validator.tmpl", renderedSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fieldInserter{target: target, rendered: rendered}
+	fi.statements("", "Validate"+naming.Model+"CreateRequest", "req == nil", "validate.Validate(req)")
+	fi.statements("", "Validate"+naming.Model+"UpdateRequest", "req == nil", "id == 0")
+	fi.missingDecls()
+	if len(fi.edits) == 0 {
+		return src, nil
+	}
+	return fi.finish(filename)
+}
+
+// AddServiceFields inserts fields into an existing service file: the sortable fields in
+// applySorting, the model literal in Create and the assignments in Update.
+func AddServiceFields(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	filename := "service.go"
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSrc, err := RenderTemplate("service.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := parseGoSource("service.tmpl", renderedSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fieldInserter{target: target, rendered: rendered}
+
+	if existingFn, renderedFn := findMethod(target.file, naming.Service, "applySorting"), findMethod(rendered.file, naming.Service, "applySorting"); existingFn != nil && renderedFn != nil {
+		fi.litElements(findAssignedLit(existingFn.Body, "validSortFields"), findAssignedLit(renderedFn.Body, "validSortFields"))
+	}
+
+	if existingFn, renderedFn := findMethod(target.file, naming.Service, "Create"), findMethod(rendered.file, naming.Service, "Create"); existingFn != nil && renderedFn != nil {
+		fi.litElements(findCompositeLit(existingFn.Body, naming.Model), findCompositeLit(renderedFn.Body, naming.Model))
+	}
+
+	fi.statements(naming.Service, "Update", "Validate"+naming.Model+"UpdateRequest", "s.DB.Save(item)")
+
+	return fi.finish(filename)
+}
+
+// AddTestHelperFields inserts the setup that new fields need into the generated test helpers,
+// such as migrating the tables of hasMany relations in setupModule
+func AddTestHelperFields(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	filename := "helpers_test.go"
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSrc, err := RenderTemplate("test_helpers.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := parseGoSource("test_helpers.tmpl", renderedSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fieldInserter{target: target, rendered: rendered}
+	fi.statements("", "setupModule", "mod.Migrate()", "return mod")
+	for _, name := range []string{"newCreateRequest", "newIndexedCreateRequest", "newUpdateRequest"} {
+		existingFn, renderedFn := findMethod(target.file, "", name), findMethod(rendered.file, "", name)
+		if existingFn != nil && renderedFn != nil {
+			fi.litElements(findCompositeLit(existingFn.Body, "Create"+naming.Model+"Request"), findCompositeLit(renderedFn.Body, "Create"+naming.Model+"Request"))
+			fi.litElements(findCompositeLit(existingFn.Body, "Update"+naming.Model+"Request"), findCompositeLit(renderedFn.Body, "Update"+naming.Model+"Request"))
+		}
+	}
+	return fi.finish(filename)
+}
+
+// AddServiceTestFields inserts the checks of new fields into the generated Create and Update
+// service tests
+func AddServiceTestFields(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	filename := "service_test.go"
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedSrc, err := RenderTemplate("service_test.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := parseGoSource("service_test.tmpl", renderedSrc)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := &fieldInserter{target: target, rendered: rendered}
+	fi.statements("", "TestCreate"+naming.Model, "item.Id == 0", "")
+	fi.statements("", "TestUpdate"+naming.Model, "item.Id != created.Id", "")
+	if len(fi.edits) == 0 {
+		return src, nil
+	}
+	return fi.finish(filename)
+}
+
+// sortParamDoc matches the Swagger comment documenting the sort fields of a list endpoint
+var sortParamDoc = regexp.MustCompile(`(?m)^[ \t]*// @Param sort query .*$`)
+
+// UpdateSortDocs rewrites the Swagger comments documenting the sort fields in a controller to
+// those controller.tmpl renders for fields, the module's complete list of fields
+func UpdateSortDocs(src []byte, naming *NamingConvention, fields []Field) ([]byte, error) {
+	rendered, err := RenderTemplate("controller.tmpl", naming, fields)
+	if err != nil {
+		return nil, err
+	}
+	doc := sortParamDoc.Find(rendered)
+	if doc == nil {
+		return src, nil
+	}
+	return sortParamDoc.ReplaceAllLiteral(src, doc), nil
+}
+
+// HasManyField returns the name of the field of the model in src that holds the related models
+// through foreignKey, or "" when there is none. Fields without a foreignKey or polymorphicId
+// tag use the GORM default, the model name followed by Id.
+func HasManyField(modelSrc []byte, naming *NamingConvention, related, foreignKey string) (string, error) {
+	target, err := parseGoSource(naming.ModelSnake+".go", modelSrc)
+	if err != nil {
+		return "", err
+	}
+	if findStruct(target.file, naming.Model) == nil {
+		return "", fmt.Errorf("struct %s not found", naming.Model)
+	}
+
+	for _, rel := range relationKeys(target.file, related) {
+		if rel.owner == naming.Model && rel.many && rel.key == foreignKey {
+			return rel.field.Names[0].Name, nil
+		}
+	}
+	return "", nil
+}
+
+// relationKey is a relation field of a struct that names a field of the related model: the
+// foreign key of a slice of related models, or the reference of a belongsTo relation
+type relationKey struct {
+	owner  string
+	field  *ast.Field
+	many   bool
+	option string // the gorm option naming key, "" for the GORM default
+	key    string
+}
+
+// relationKeys returns the relation fields of the structs in file that name a field of related.
+// Slices without a foreignKey or polymorphicId tag use the GORM default, the owner followed by
+// Id, and many2many relations name no field of related.
+func relationKeys(file *ast.File, related string) []relationKey {
+	var keys []relationKey
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				elem, many := field.Type, false
+				if array, ok := elem.(*ast.ArrayType); ok {
+					elem, many = array.Elt, true
+				}
+				if star, ok := elem.(*ast.StarExpr); ok {
+					elem = star.X
+				}
+				if sel, ok := elem.(*ast.SelectorExpr); ok {
+					elem = sel.Sel
+				}
+				if ident, ok := elem.(*ast.Ident); !ok || ident.Name != related || len(field.Names) == 0 {
+					continue
+				}
+
+				rel := relationKey{owner: ts.Name.Name, field: field, many: many}
+				if many {
+					rel.key = ts.Name.Name + "Id"
+				}
+				if field.Tag != nil {
+					tag, _ := strconv.Unquote(field.Tag.Value)
+					for _, option := range strings.Split(reflect.StructTag(tag).Get("gorm"), ";") {
+						name, value, _ := strings.Cut(option, ":")
+						switch name = strings.TrimSpace(name); {
+						case many && (strings.EqualFold(name, "foreignKey") || strings.EqualFold(name, "polymorphicId")):
+							rel.option, rel.key = name, strings.TrimSpace(value)
+						case !many && strings.EqualFold(name, "references"):
+							rel.option, rel.key = name, strings.TrimSpace(value)
+						case strings.HasPrefix(strings.ToLower(name), "many2many"):
+							rel.key = ""
+						}
+					}
+				}
+				if rel.key != "" {
+					keys = append(keys, rel)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// RenameRelationKeys renames fields of related in the foreignKey and references tags of the
+// relations in src that name them, such as the inverse hasMany relations of other models
+func RenameRelationKeys(filename string, src []byte, related string, renames map[string]string) ([]byte, error) {
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []sourceEdit
+	for _, rel := range relationKeys(target.file, related) {
+		newKey, ok := renames[rel.key]
+		if !ok || rel.option == "" || strings.EqualFold(rel.option, "polymorphicId") {
+			continue
+		}
+		tag := rel.field.Tag.Value
+		for _, end := range []string{`"`, `;`} {
+			tag = strings.Replace(tag, rel.option+":"+rel.key+end, rel.option+":"+newKey+end, 1)
+		}
+		edits = append(edits, sourceEdit{target.offset(rel.field.Tag.Pos()), target.offset(rel.field.Tag.End()), tag})
+	}
+	if len(edits) == 0 {
+		return src, nil
+	}
+	return format.Source(applyEdits(src, edits))
+}
+
+// FindRelationKeys lists the relations in a Go file that name one of the fields of related as
+// their foreign key or reference, including slices that rely on the GORM default foreign key
+func FindRelationKeys(filename string, src []byte, related string, names []string) ([]string, error) {
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	for _, rel := range relationKeys(target.file, related) {
+		if slices.Contains(names, rel.key) {
+			line := target.fset.Position(rel.field.Pos()).Line
+			refs = append(refs, fmt.Sprintf("%s:%d: %s", filename, line, strings.TrimSpace(lineAt(src, target.offset(rel.field.Pos())))))
+		}
+	}
+	return refs, nil
+}
+
+// enumTypes copies the declarations of the enum types of fields that the existing file lacks:
+// the type, its constants and values and its methods. They go after the model struct and the
+// enums already declared there, where the template renders them.
+func (fi *fieldInserter) enumTypes(naming *NamingConvention, fields []Field) error {
+	var blocks []string
+	for _, field := range fields {
+		if len(field.EnumValues) == 0 || len(enumDecls(fi.target.file, field.Type)) > 0 {
+			continue
+		}
+		for _, decl := range enumDecls(fi.rendered.file, field.Type) {
+			blocks = append(blocks, fi.rendered.declText(decl))
+		}
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	decls := fi.target.file.Decls
+	last := slices.IndexFunc(decls, func(decl ast.Decl) bool {
+		gen, ok := decl.(*ast.GenDecl)
+		return ok && gen.Tok == token.TYPE && declOwner(gen) == naming.Model
+	})
+	if last < 0 {
+		return fmt.Errorf("struct %s not found", naming.Model)
+	}
+	enums := enumTypeNames(fi.target.file)
+	for last+1 < len(decls) && enums[declOwner(decls[last+1])] {
+		last++
+	}
+
+	offset := fi.target.offset(decls[last].End())
+	fi.edits = append(fi.edits, sourceEdit{start: offset, end: offset, text: "\n\n" + strings.Join(blocks, "\n\n")})
+	return nil
+}
+
+// missingDecls appends the top-level functions and variables of the rendered file that the
+// existing file lacks, such as the helpers of new validator checks, where the template renders them
+func (fi *fieldInserter) missingDecls() {
+	declared := make(map[string]bool)
+	for _, decl := range fi.target.file.Decls {
+		for _, name := range declNames(decl) {
+			declared[name] = true
+		}
+	}
+
+	var blocks []string
+	for _, decl := range fi.rendered.file.Decls {
+		names := declNames(decl)
+		if len(names) > 0 && !slices.ContainsFunc(names, func(name string) bool { return declared[name] }) {
+			blocks = append(blocks, fi.rendered.declText(decl))
+		}
+	}
+	if len(blocks) > 0 {
+		end := len(fi.target.src)
+		fi.edits = append(fi.edits, sourceEdit{start: end, end: end, text: "\n" + strings.Join(blocks, "\n\n") + "\n"})
+	}
+}
+
+// declNames returns the names of the functions and variables a top-level declaration declares
+func declNames(decl ast.Decl) []string {
+	var names []string
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			names = append(names, d.Name.Name)
+		}
+	case *ast.GenDecl:
+		if d.Tok != token.VAR {
+			break
+		}
+		for _, spec := range d.Specs {
+			for _, name := range spec.(*ast.ValueSpec).Names {
+				names = append(names, name.Name)
+			}
+		}
+	}
+	return names
+}
+
+// declText returns the source of a top-level declaration with its doc comment
+func (s *goSource) declText(decl ast.Decl) string {
+	start := decl.Pos()
+	switch d := decl.(type) {
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	return string(s.src[s.offset(start):s.offset(decl.End())])
+}
+
+// deleteDecl returns an edit removing a top-level declaration with its doc comment and the
+// blank line after it
+func (s *goSource) deleteDecl(decl ast.Decl) sourceEdit {
+	end := s.offset(decl.End())
+	start := end - len(s.declText(decl))
+	for i := 0; i < 2 && end < len(s.src) && s.src[end] == '\n'; i++ {
+		end++
+	}
+	return sourceEdit{start: start, end: end}
+}
+
+// declOwner returns the type a top-level declaration belongs to: the declared type, the type
+// of declared constants, the type a Values list is named after, or the receiver of a method
+func declOwner(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) == 1 {
+			return receiverName(d.Recv.List[0].Type)
+		}
+	case *ast.GenDecl:
+		if len(d.Specs) == 0 {
+			return ""
+		}
+		switch spec := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		case *ast.ValueSpec:
+			if ident, ok := spec.Type.(*ast.Ident); ok {
+				return ident.Name
+			}
+			if name, ok := strings.CutSuffix(spec.Names[0].Name, "Values"); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// enumTypeNames returns the enum types declared in file, those with an IsValid method
+func enumTypeNames(file *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == "IsValid" {
+			if owner := declOwner(fn); owner != "" {
+				names[owner] = true
+			}
+		}
+	}
+	return names
+}
+
+// enumDecls returns the top-level declarations belonging to the enum type name
+func enumDecls(file *ast.File, name string) []ast.Decl {
+	if !enumTypeNames(file)[name] {
+		return nil
+	}
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		if declOwner(decl) == name {
+			decls = append(decls, decl)
+		}
+	}
+	return decls
+}
+
+// fieldScope names the declarations of a module that hold per-field code
+type fieldScope struct {
+	structs  map[string]bool // Structs whose fields mirror the model
+	literals map[string]bool // Composite literal types whose keys are field names
+	funcs    map[string]bool // Functions holding per-field statements
+	fieldFns [][2]string     // Prefix and suffix around the field in the names of per-field funcs
+}
+
+// fieldFunc returns the field a per-field func such as VerifyPassword or the test
+// TestPostRejectsInvalidStatus is about, with the prefix and suffix of the name around it
+func (s fieldScope) fieldFunc(funcName string) (string, [2]string, bool) {
+	for _, affix := range s.fieldFns {
+		rest, ok := strings.CutPrefix(funcName, affix[0])
+		if !ok {
+			continue
+		}
+		if field, ok := strings.CutSuffix(rest, affix[1]); ok && field != "" {
+			return field, affix, true
+		}
+	}
+	return "", [2]string{}, false
+}
+
+func newFieldScope(naming *NamingConvention) fieldScope {
+	return fieldScope{
+		structs: map[string]bool{
+			naming.Model:                        true,
+			"Create" + naming.Model + "Request": true,
+			"Update" + naming.Model + "Request": true,
+			naming.Model + "Response":           true,
+			naming.Model + "ListResponse":       true,
+			naming.Model + "ModelResponse":      true,
+		},
+		literals: map[string]bool{
+			naming.Model:                        true,
+			naming.Model + "Response":           true,
+			naming.Model + "ListResponse":       true,
+			naming.Model + "ModelResponse":      true,
+			"Create" + naming.Model + "Request": true,
+			"Update" + naming.Model + "Request": true,
+		},
+		funcs: map[string]bool{
+			"ToResponse": true,
+			"Preload":    true,
+			"Create":     true,
+			"Update":     true,
+
+			"Validate" + naming.Model + "CreateRequest": true,
+			"Validate" + naming.Model + "UpdateRequest": true,
+
+			"TestCreate" + naming.Model: true,
+			"TestUpdate" + naming.Model: true,
+			"newCreateRequest":          true,
+			"newIndexedCreateRequest":   true,
+			"newUpdateRequest":          true,
+		},
+		fieldFns: [][2]string{
+			{"Verify", ""},
+			{"TestCreate" + naming.Model + "RejectsDuplicate", ""},
+			{"TestCreate" + naming.Model + "RejectsUnknown", ""},
+			{"TestCreate" + naming.Model + "Defaults", ""},
+			{"Test" + naming.Model + "RejectsInvalid", ""},
+			{"Test" + naming.Model + "Hashes", ""},
+			{"Test" + naming.Model, "Links"},
+		},
+	}
+}
+
+// litTypeName returns the type name of a composite literal, without package qualifier
+func litTypeName(lit *ast.CompositeLit) string {
+	switch t := lit.Type.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+// isSortFieldsLit reports whether n assigns the validSortFields map, returning its literal
+func isSortFieldsLit(n ast.Node) (*ast.CompositeLit, bool) {
+	assign, ok := n.(*ast.AssignStmt)
+	if !ok {
+		return nil, false
+	}
+	lit := findAssignedLit(assign, "validSortFields")
+	return lit, lit != nil
+}
+
+// preloadName returns the relation preloaded by a statement such as query = query.Preload("Author")
+func preloadName(stmt ast.Stmt) string {
+	var name string
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return name == ""
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Preload" {
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				name, _ = strconv.Unquote(lit.Value)
+			}
+		}
+		return name == ""
+	})
+	return name
+}
+
+// selectsField reports whether an expression selects one of the named fields, e.g. req.Title
+func selectsField(node ast.Node, names map[string]bool) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok && names[sel.Sel.Name] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// callsWithField reports whether expr is a call taking one of the named fields
+func callsWithField(expr ast.Expr, names map[string]bool) bool {
+	call, ok := expr.(*ast.CallExpr)
+	return ok && slices.ContainsFunc(call.Args, func(arg ast.Expr) bool { return selectsField(arg, names) })
+}
+
+// isErrCheck reports whether stmt is an if err != nil check
+func isErrCheck(stmt ast.Stmt) bool {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil {
+		return false
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ {
+		return false
+	}
+	x, ok := cond.X.(*ast.Ident)
+	y, ok2 := cond.Y.(*ast.Ident)
+	return ok && ok2 && x.Name == "err" && y.Name == "nil"
+}
+
+// deleteNode returns an edit removing node. When node is alone on its line(s) the whole lines
+// are removed, together with the comment line directly above it if withComment is set.
+func (s *goSource) deleteNode(node ast.Node, withComment bool) sourceEdit {
+	start, end := s.offset(node.Pos()), s.offset(node.End())
+
+	// Swallow a trailing comma
+	rest := end
+	for rest < len(s.src) && (s.src[rest] == ' ' || s.src[rest] == '\t') {
+		rest++
+	}
+	if rest < len(s.src) && s.src[rest] == ',' {
+		end = rest + 1
+	}
+
+	lineStart := bytes.LastIndexByte(s.src[:start], '\n') + 1
+	lineEnd := len(s.src)
+	if i := bytes.IndexByte(s.src[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	after := strings.TrimSpace(string(s.src[end:lineEnd]))
+	if strings.TrimSpace(string(s.src[lineStart:start])) != "" || (after != "" && !strings.HasPrefix(after, "//")) {
+		// Shares its line with other code, remove just the node
+		for end < len(s.src) && s.src[end] == ' ' {
+			end++
+		}
+		return sourceEdit{start: start, end: end}
+	}
+
+	if withComment && lineStart > 0 {
+		prevStart := bytes.LastIndexByte(s.src[:lineStart-1], '\n') + 1
+		if strings.HasPrefix(strings.TrimSpace(string(s.src[prevStart:lineStart])), "//") {
+			lineStart = prevStart
+		}
+	}
+	return sourceEdit{start: lineStart, end: lineEnd}
+}
+
+// dropNestedEdits removes edits that fall inside another edit's range
+func dropNestedEdits(edits []sourceEdit) []sourceEdit {
+	var kept []sourceEdit
+	for i, edit := range edits {
+		nested := false
+		for j, other := range edits {
+			if i != j && other.start <= edit.start && edit.end <= other.end && other.end-other.start > edit.end-edit.start {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			kept = append(kept, edit)
+		}
+	}
+	return kept
+}
+
+// formatWithoutUnusedImports drops imports no longer referenced and formats the file
+func formatWithoutUnusedImports(filename string, src []byte) ([]byte, error) {
+	result, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	ast.Inspect(result.file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	// DeleteNamedImport shrinks file.Imports, so iterate over a copy
+	for _, imp := range slices.Clone(result.file.Imports) {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(importPath)
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		if name != "_" && name != "." && !used[name] {
+			astutil.DeleteNamedImport(result.fset, result.file, importName(imp), importPath)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, result.fset, result.file); err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func importName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		return imp.Name.Name
+	}
+	return ""
+}
+
+// ResolveFieldNames returns the Go names a field occupies in the model struct. A belongsTo
+// relation occupies both the foreign key (AuthorId) and the object (Author).
+func ResolveFieldNames(modelSrc []byte, naming *NamingConvention, field string) ([]string, error) {
+	target, err := parseGoSource(naming.ModelSnake+".go", modelSrc)
+	if err != nil {
+		return nil, err
+	}
+	model := findStruct(target.file, naming.Model)
+	if model == nil {
+		return nil, fmt.Errorf("struct %s not found", naming.Model)
+	}
+
+	existing := structFieldNames(model)
+	name := ToPascalCase(field)
+	if !existing[name] && !existing[name+"Id"] {
+		return nil, fmt.Errorf("field %s not found in %s", name, naming.Model)
+	}
+
+	var names []string
+	for _, candidate := range []string{TrimIdSuffix(name), name, TrimIdSuffix(name) + "Id"} {
+		if existing[candidate] && !containsString(names, candidate) {
+			names = append(names, candidate)
+		}
+	}
+	return names, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveFields removes the named fields from a module file: the model, request and response
+// structs, the ToResponse/ToListResponse and Create literals, the requests built by the test
+// helpers, validSortFields, the per-field statements in ToResponse, Preload, Update, the
+// validators and the Create/Update tests, the tests of the fields themselves and their enum
+// types. Imports left unused are dropped.
+func RemoveFields(filename string, src []byte, naming *NamingConvention, names []string) ([]byte, error) {
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := newFieldScope(naming)
+	remove := make(map[string]bool)
+	columns := make(map[string]bool)
+	for _, name := range names {
+		remove[name] = true
+		columns[ToSnakeCase(name)] = true
+	}
+
+	var edits []sourceEdit
+	// The enum types of removed fields go with them
+	if model := findStruct(target.file, naming.Model); model != nil {
+		enums := enumTypeNames(target.file)
+		for _, field := range model.Fields.List {
+			ident, ok := field.Type.(*ast.Ident)
+			if len(field.Names) != 1 || !remove[field.Names[0].Name] || !ok || !enums[ident.Name] {
+				continue
+			}
+			for _, decl := range enumDecls(target.file, ident.Name) {
+				edits = append(edits, target.deleteDecl(decl))
+			}
+		}
+	}
+
+	ast.Inspect(target.file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.TypeSpec:
+			if st, ok := node.Type.(*ast.StructType); ok && scope.structs[node.Name.Name] {
+				for _, field := range st.Fields.List {
+					if len(field.Names) == 1 && remove[field.Names[0].Name] {
+						edits = append(edits, target.deleteNode(field, false))
+					}
+				}
+			}
+		case *ast.CompositeLit:
+			if scope.literals[litTypeName(node)] {
+				for _, elt := range node.Elts {
+					if remove[litKey(elt)] {
+						edits = append(edits, target.deleteNode(elt, false))
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			// The funcs and tests of a removed field go with it
+			if field, _, ok := scope.fieldFunc(node.Name.Name); ok && remove[field] {
+				edits = append(edits, target.deleteDecl(node))
+				return false
+			}
+			if node.Body == nil || !scope.funcs[node.Name.Name] {
+				return true
+			}
+			for i, stmt := range node.Body.List {
+				if ifStmt, ok := stmt.(*ast.IfStmt); ok && selectsField(ifStmt.Cond, remove) {
+					edits = append(edits, target.deleteNode(stmt, true))
+				} else if remove[preloadName(stmt)] {
+					edits = append(edits, target.deleteNode(stmt, false))
+				} else if assign, ok := stmt.(*ast.AssignStmt); ok && assign.Tok == token.DEFINE && len(assign.Rhs) == 1 && callsWithField(assign.Rhs[0], remove) {
+					// e.g. passwordHash, err := hashSecret(req.Password) with its error check
+					edits = append(edits, target.deleteNode(stmt, false))
+					if i+1 < len(node.Body.List) && isErrCheck(node.Body.List[i+1]) {
+						edits = append(edits, target.deleteNode(node.Body.List[i+1], false))
+					}
+				}
+			}
+		}
+
+		if lit, ok := isSortFieldsLit(n); ok {
+			for _, elt := range lit.Elts {
+				if columns[litKey(elt)] {
+					edits = append(edits, target.deleteNode(elt, false))
+				}
+			}
+			return false
+		}
+		return true
+	})
+
+	edited, err := dropOrphanedFuncs(filename, applyEdits(src, dropNestedEdits(edits)), referencedFuncs(target.file))
+	if err != nil {
+		return nil, err
+	}
+	return formatWithoutUnusedImports(filename, edited)
+}
+
+// referencedFuncs returns the top-level functions of a file that are used in it
+func referencedFuncs(file *ast.File) map[string]bool {
+	uses := make(map[string]int)
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+			uses[fn.Name.Name] = 0
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if _, ok := uses[ident.Name]; ok {
+				uses[ident.Name]++
+			}
+		}
+		return true
+	})
+
+	referenced := make(map[string]bool)
+	for name, count := range uses {
+		// The declaration itself is one of the uses
+		if count > 1 {
+			referenced[name] = true
+		}
+	}
+	return referenced
+}
+
+// dropOrphanedFuncs removes the unexported top-level functions that were used before an edit
+// and no longer are, such as hashSecret once the last secret field is removed
+func dropOrphanedFuncs(filename string, src []byte, before map[string]bool) ([]byte, error) {
+	result, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	after := referencedFuncs(result.file)
+	var edits []sourceEdit
+	for _, decl := range result.file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if ok && fn.Recv == nil && !ast.IsExported(fn.Name.Name) && before[fn.Name.Name] && !after[fn.Name.Name] {
+			edits = append(edits, result.deleteDecl(decl))
+		}
+	}
+	return applyEdits(src, edits), nil
+}
+
+// RenameFields renames fields in a module file. renames maps old Go names to new ones;
+// struct fields, their json tags and foreign keys, selectors, literal keys, validSortFields,
+// Preload calls, the columns in Select, Order, Where and Update calls, the field names in the
+// messages of the validators and tests and the names of per-field tests are updated. Selectors
+// of HTTP requests and recorders, such as rec.Body in the tests, are left alone.
+func RenameFields(filename string, src []byte, naming *NamingConvention, renames map[string]string) ([]byte, error) {
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := newFieldScope(naming)
+	columns := make(map[string]string)
+	for oldName, newName := range renames {
+		columns[ToSnakeCase(oldName)] = ToSnakeCase(newName)
+	}
+
+	httpSelectors := findHTTPSelectors(target.file)
+	var edits []sourceEdit
+	replace := func(node ast.Node, text string) {
+		edits = append(edits, sourceEdit{start: target.offset(node.Pos()), end: target.offset(node.End()), text: text})
+	}
+	renamedLits := make(map[*ast.BasicLit]bool)
+	replaceLit := func(lit *ast.BasicLit, rename func(value string) string) {
+		if lit.Kind != token.STRING || renamedLits[lit] {
+			return
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return
+		}
+		if renamed := rename(value); renamed != value {
+			renamedLits[lit] = true
+			replace(lit, strconv.Quote(renamed))
+		}
+	}
+	// Columns are whole words in query strings such as "id, title" or "title ASC"
+	renameColumns := func(value string) string {
+		for oldColumn, newColumn := range columns {
+			value = regexp.MustCompile(`\b`+regexp.QuoteMeta(oldColumn)+`\b`).ReplaceAllString(value, newColumn)
+		}
+		return value
+	}
+	// Messages and test values start with the field, e.g. "title must be ...",
+	// "Title: got %v, want %v" or "Test Title %d"
+	renameMessage := func(value string) string {
+		for oldColumn, newColumn := range columns {
+			if value == oldColumn {
+				return newColumn
+			}
+			if rest, ok := strings.CutPrefix(value, oldColumn+" "); ok {
+				return newColumn + " " + rest
+			}
+		}
+		for oldName, newName := range renames {
+			if rest, ok := strings.CutPrefix(value, oldName+": "); ok {
+				return newName + ": " + rest
+			}
+			for _, prefix := range []string{"Test ", "Updated "} {
+				if rest, ok := strings.CutPrefix(value, prefix+oldName); ok && (rest == "" || strings.HasPrefix(rest, " ")) {
+					return prefix + newName + rest
+				}
+			}
+		}
+		return value
+	}
+
+	ast.Inspect(target.file, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.TypeSpec:
+			st, ok := node.Type.(*ast.StructType)
+			if ok && node.Name.Name == naming.Model+"SelectOption" {
+				// The display name notes the field it comes from
+				for _, field := range st.Fields.List {
+					for _, comment := range commentList(field.Comment) {
+						for oldName, newName := range renames {
+							if text := strings.Replace(comment.Text, "From "+oldName+" field", "From "+newName+" field", 1); text != comment.Text {
+								replace(comment, text)
+							}
+						}
+					}
+				}
+				return false
+			}
+			if !ok || !scope.structs[node.Name.Name] {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				if len(field.Names) != 1 {
+					continue
+				}
+				if newName, ok := renames[field.Names[0].Name]; ok {
+					replace(field.Names[0], newName)
+				}
+				if field.Tag != nil {
+					if tag := renameInTag(field.Tag.Value, renames, columns); tag != field.Tag.Value {
+						replace(field.Tag, tag)
+					}
+				}
+			}
+			return false
+		case *ast.CompositeLit:
+			if scope.literals[litTypeName(node)] {
+				for _, elt := range node.Elts {
+					if newName, ok := renames[litKey(elt)]; ok {
+						replace(elt.(*ast.KeyValueExpr).Key, newName)
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			if newName, ok := renames[node.Sel.Name]; ok && !httpSelectors[node] {
+				replace(node.Sel, newName)
+			}
+		case *ast.CallExpr:
+			sel, ok := node.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "Preload":
+				if len(node.Args) == 1 {
+					if lit, ok := node.Args[0].(*ast.BasicLit); ok {
+						replaceLit(lit, func(value string) string {
+							if newName, ok := renames[value]; ok {
+								return newName
+							}
+							return value
+						})
+					}
+				}
+			case "Select", "Order", "Where", "Update":
+				for _, arg := range node.Args {
+					if lit, ok := arg.(*ast.BasicLit); ok {
+						replaceLit(lit, renameColumns)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if field, affix, ok := scope.fieldFunc(node.Name.Name); ok {
+				if newName, ok := renames[field]; ok {
+					replace(node.Name, affix[0]+newName+affix[1])
+				}
+			}
+			if node.Body != nil && scope.funcs[node.Name.Name] {
+				ast.Inspect(node.Body, func(n ast.Node) bool {
+					// Validation tags such as "email" name the rule, not the field
+					if kv, ok := n.(*ast.KeyValueExpr); ok && litKey(kv) == "Tag" {
+						return false
+					}
+					// Query strings are handled as calls
+					if call, ok := n.(*ast.CallExpr); ok {
+						if sel, ok := call.Fun.(*ast.SelectorExpr); ok && slices.Contains([]string{"Select", "Order", "Where", "Update"}, sel.Sel.Name) {
+							return false
+						}
+					}
+					if lit, ok := n.(*ast.BasicLit); ok {
+						replaceLit(lit, renameMessage)
+					}
+					return true
+				})
+			}
+		}
+
+		if lit, ok := isSortFieldsLit(n); ok {
+			for _, elt := range lit.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				for _, part := range []ast.Expr{kv.Key, kv.Value} {
+					if value, ok := part.(*ast.BasicLit); ok {
+						unquoted, _ := strconv.Unquote(value.Value)
+						if column, ok := columns[unquoted]; ok {
+							replace(value, strconv.Quote(column))
+						}
+					}
+				}
+			}
+			return false
+		}
+		return true
+	})
+
+	updated := applyEdits(src, edits)
+	formatted, err := format.Source(updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s: %w", filename, err)
+	}
+	return formatted, nil
+}
+
+// commentList returns the comments of a group, which may be nil
+func commentList(group *ast.CommentGroup) []*ast.Comment {
+	if group == nil {
+		return nil
+	}
+	return group.List
+}
+
+// findHTTPSelectors returns the selectors whose operand is an HTTP request or response
+// recorder: a parameter of an http or httptest type, or a variable assigned from httptest or
+// the doRequest test helper
+func findHTTPSelectors(file *ast.File) map[*ast.SelectorExpr]bool {
+	isHTTP := func(expr ast.Expr) bool {
+		if star, ok := expr.(*ast.StarExpr); ok {
+			expr = star.X
+		}
+		if call, ok := expr.(*ast.CallExpr); ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok {
+				return ident.Name == "doRequest"
+			}
+			expr = call.Fun
+		}
+		if sel, ok := expr.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				return pkg.Name == "http" || pkg.Name == "httptest"
+			}
+		}
+		return false
+	}
+
+	selectors := make(map[*ast.SelectorExpr]bool)
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		vars := make(map[string]bool)
+		for _, param := range fn.Type.Params.List {
+			if isHTTP(param.Type) {
+				for _, name := range param.Names {
+					vars[name.Name] = true
+				}
+			}
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if assign, ok := n.(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+				for i, rhs := range assign.Rhs {
+					if ident, ok := assign.Lhs[i].(*ast.Ident); ok && isHTTP(rhs) {
+						vars[ident.Name] = true
+					}
+				}
+			}
+			return true
+		})
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && vars[ident.Name] {
+					selectors[sel] = true
+				}
+			}
+			return true
+		})
+	}
+	return selectors
+}
+
+// renameInTag rewrites the json name and gorm foreign key of a struct tag literal
+func renameInTag(tag string, renames, columns map[string]string) string {
+	for oldColumn, newColumn := range columns {
+		tag = strings.Replace(tag, `json:"`+oldColumn+`"`, `json:"`+newColumn+`"`, 1)
+		tag = strings.Replace(tag, `json:"`+oldColumn+`,`, `json:"`+newColumn+`,`, 1)
+	}
+	for oldName, newName := range renames {
+		for _, end := range []string{`"`, `;`} {
+			tag = strings.Replace(tag, "foreignKey:"+oldName+end, "foreignKey:"+newName+end, 1)
+		}
+	}
+	return tag
+}
+
+// FindFieldReferences lists the places in a Go file that still mention one of the named fields,
+// either as an identifier or as a snake_case word inside a string literal
+func FindFieldReferences(filename string, src []byte, names []string) ([]string, error) {
+	target, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	idents := make(map[string]bool)
+	var columns []string
+	for _, name := range names {
+		idents[name] = true
+		columns = append(columns, regexp.QuoteMeta(ToSnakeCase(name)))
+	}
+	columnPattern := regexp.MustCompile(`\b(` + strings.Join(columns, "|") + `)\b`)
+
+	httpSelectors := findHTTPSelectors(target.file)
+	var refs []string
+	seen := make(map[int]bool)
+	ast.Inspect(target.file, func(n ast.Node) bool {
+		// Only identifiers that name a field count; a type can share the name of a relation
+		var pos token.Pos
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if idents[node.Sel.Name] && !httpSelectors[node] {
+				pos = node.Sel.Pos()
+			}
+		case *ast.KeyValueExpr:
+			if key, ok := node.Key.(*ast.Ident); ok && idents[key.Name] {
+				pos = key.Pos()
+			}
+		case *ast.Field:
+			if len(node.Names) == 1 && idents[node.Names[0].Name] {
+				pos = node.Names[0].Pos()
+			}
+		case *ast.BasicLit:
+			if node.Kind == token.STRING && columnPattern.MatchString(node.Value) {
+				pos = node.Pos()
+			}
+		}
+		if pos.IsValid() {
+			line := target.fset.Position(pos).Line
+			if !seen[line] {
+				seen[line] = true
+				refs = append(refs, fmt.Sprintf("%s:%d: %s", filename, line, strings.TrimSpace(lineAt(src, target.offset(pos)))))
+			}
+		}
+		return true
+	})
+	return refs, nil
+}
+
+// lineAt returns the line of src containing offset
+func lineAt(src []byte, offset int) string {
+	start := bytes.LastIndexByte(src[:offset], '\n') + 1
+	end := len(src)
+	if i := bytes.IndexByte(src[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	return string(src[start:end])
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// moduleFiles are the generated files the field commands edit, by template
+var moduleFiles = []struct {
+	template string
+	filename string
+}{
+	{"model.tmpl", "post.go"},
+	{"service.tmpl", "service.go"},
+	{"validator.tmpl", "validator.go"},
+	{"test_helpers.tmpl", "helpers_test.go"},
+	{"service_test.tmpl", "service_test.go"},
+}
+
+// renderFile renders a template for the field definitions the way base g does
+func renderFile(t *testing.T, templateName, filename string, naming *NamingConvention, defs ...string) []byte {
+	t.Helper()
+	td, err := NewTemplateData(naming.Model, defs)
+	if err != nil {
+		t.Fatalf("NewTemplateData(%q): %v", defs, err)
+	}
+	src, err := RenderTemplate(templateName, naming, td.Fields)
+	if err != nil {
+		t.Fatalf("RenderTemplate(%s): %v", templateName, err)
+	}
+	formatted, err := FormatGoFile(filename, src)
+	if err != nil {
+		t.Fatalf("%s does not parse: %v", templateName, err)
+	}
+	return formatted
+}
+
+// parseFields returns the fields of the named struct in src, failing when src does not parse
+func parseFields(t *testing.T, filename string, src []byte, structName string) map[string]bool {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("%s does not parse: %v\n%s", filename, err, src)
+	}
+	st := findStruct(file, structName)
+	if st == nil {
+		t.Fatalf("struct %s not found in %s", structName, filename)
+	}
+	return structFieldNames(st)
+}
+
+// expectGenerated fails when got differs from what the templates generate
+func expectGenerated(t *testing.T, filename string, got, want []byte) {
+	t.Helper()
+	if diff := UnifiedDiff("generated/"+filename, "edited/"+filename, want, got); diff != "" {
+		t.Errorf("%s differs from the generated file:\n%s", filename, diff)
+	}
+}
+
+// addFieldEdits returns the edits base g field makes to each module file
+func addFieldEdits(naming *NamingConvention, fields []Field) map[string]func(src []byte) ([]byte, error) {
+	return map[string]func(src []byte) ([]byte, error){
+		"post.go":         func(src []byte) ([]byte, error) { return AddModelFields(src, naming, fields) },
+		"service.go":      func(src []byte) ([]byte, error) { return AddServiceFields(src, naming, fields) },
+		"validator.go":    func(src []byte) ([]byte, error) { return AddValidatorFields(src, naming, fields) },
+		"helpers_test.go": func(src []byte) ([]byte, error) { return AddTestHelperFields(src, naming, fields) },
+		"service_test.go": func(src []byte) ([]byte, error) { return AddServiceTestFields(src, naming, fields) },
+	}
+}
+
+func TestAddFieldsMatchesGeneration(t *testing.T) {
+	naming := NewNamingConvention("Post")
+	existing := []string{"title:string:required", "body:text"}
+	added := []string{"views:int", "author:belongsTo:User", "published:bool"}
+	td, err := NewTemplateData(naming.Model, added)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits := addFieldEdits(naming, td.Fields)
+	for _, file := range moduleFiles {
+		t.Run(file.filename, func(t *testing.T) {
+			src := renderFile(t, file.template, file.filename, naming, existing...)
+			got, err := edits[file.filename](src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectGenerated(t, file.filename, got, renderFile(t, file.template, file.filename, naming, append(existing, added...)...))
+		})
+	}
+}
+
+// Fields with their own tests or imports are added to every file, though their tests are not
+func TestAddFieldsKeepsFilesValid(t *testing.T) {
+	naming := NewNamingConvention("Post")
+	td, err := NewTemplateData(naming.Model, []string{"status:enum(draft,live)", "email:email", "password:password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits := addFieldEdits(naming, td.Fields)
+	for _, file := range moduleFiles {
+		t.Run(file.filename, func(t *testing.T) {
+			src := renderFile(t, file.template, file.filename, naming, "title:string")
+			got, err := edits[file.filename](src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := parser.ParseFile(token.NewFileSet(), file.filename, got, 0); err != nil {
+				t.Fatalf("%s does not parse: %v\n%s", file.filename, err, got)
+			}
+		})
+	}
+
+	src, err := AddModelFields(renderFile(t, "model.tmpl", "post.go", naming, "title:string"), naming, td.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	structs := map[string][]string{
+		"Post":              {"Title", "Status", "Email", "Password"},
+		"CreatePostRequest": {"Title", "Status", "Email", "Password"},
+		"UpdatePostRequest": {"Title", "Status", "Email", "Password"},
+		"PostResponse":      {"Title", "Status", "Email"},
+	}
+	for structName, want := range structs {
+		fields := parseFields(t, "post.go", src, structName)
+		for _, name := range want {
+			if !fields[name] {
+				t.Errorf("%s has no %s field", structName, name)
+			}
+		}
+	}
+	if fields := parseFields(t, "post.go", src, "PostResponse"); fields["Password"] {
+		t.Error("PostResponse exposes the password")
+	}
+	if !strings.Contains(string(src), "type PostStatus string") {
+		t.Error("the PostStatus enum type is missing")
+	}
+}
+
+func TestUpdateSortDocsMatchesGeneration(t *testing.T) {
+	naming := NewNamingConvention("Post")
+	all := []string{"title:string", "views:int", "author:belongsTo:User"}
+	td, err := NewTemplateData(naming.Model, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UpdateSortDocs(renderFile(t, "controller.tmpl", "controller.go", naming, "title:string"), naming, td.Fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectGenerated(t, "controller.go", got, renderFile(t, "controller.tmpl", "controller.go", naming, all...))
+}
+
+func TestRemoveFieldsMatchesGeneration(t *testing.T) {
+	naming := NewNamingConvention("Post")
+	removed := []string{"Views", "Author", "AuthorId", "Status", "Email", "Password"}
+	for _, file := range moduleFiles {
+		t.Run(file.filename, func(t *testing.T) {
+			src := renderFile(t, file.template, file.filename, naming, "title:string:required", "body:text", "views:int",
+				"author:belongsTo:User", "status:enum(draft,live)", "email:email", "password:password")
+			got, err := RemoveFields(file.filename, src, naming, removed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectGenerated(t, file.filename, got, renderFile(t, file.template, file.filename, naming, "title:string:required", "body:text"))
+		})
+	}
+}
+
+func TestRenameFieldsMatchesGeneration(t *testing.T) {
+	naming := NewNamingConvention("Post")
+	renames := map[string]string{"Body": "Content", "Views": "Hits", "Author": "Writer", "AuthorId": "WriterId", "Email": "Contact"}
+	for _, file := range moduleFiles {
+		t.Run(file.filename, func(t *testing.T) {
+			src := renderFile(t, file.template, file.filename, naming, "title:string:required", "body:text", "views:int",
+				"author:belongsTo:User", "email:email")
+			got, err := RenameFields(file.filename, src, naming, renames)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectGenerated(t, file.filename, got, renderFile(t, file.template, file.filename, naming, "title:string:required", "content:text",
+				"hits:int", "writer:belongsTo:User", "contact:email"))
+		})
+	}
+}
+
+// relatedModels declares relations to Post that name its fields in different ways
+const relatedModels = `package models
+
+type User struct {
+	Id       uint
+	Posts    []*Post ` + "`" + `json:"posts,omitempty" gorm:"foreignKey:AuthorId"` + "`" + `
+	Drafts   []Post  ` + "`" + `gorm:"foreignKey:EditorId;constraint:OnDelete:CASCADE"` + "`" + `
+	Comments []*Post
+	Tags     []*Post ` + "`" + `gorm:"many2many:user_posts"` + "`" + `
+}
+
+type Comment struct {
+	Id       uint
+	PostSlug string
+	Post     *Post ` + "`" + `gorm:"foreignKey:PostSlug;references:Slug"` + "`" + `
+}
+`
+
+func TestRenameRelationKeys(t *testing.T) {
+	got, err := RenameRelationKeys("user.go", []byte(relatedModels), "Post", map[string]string{
+		"AuthorId": "WriterId", "EditorId": "ReviewerId", "Slug": "Handle", "PostSlug": "Other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer("foreignKey:AuthorId", "foreignKey:WriterId", "foreignKey:EditorId;", "foreignKey:ReviewerId;",
+		"references:Slug", "references:Handle").Replace(relatedModels)
+	if diff := UnifiedDiff("want", "got", []byte(want), got); diff != "" {
+		t.Errorf("unexpected relations:\n%s", diff)
+	}
+}
+
+func TestFindRelationKeys(t *testing.T) {
+	got, err := FindRelationKeys("user.go", []byte(relatedModels), "Post", []string{"AuthorId", "UserId", "Slug", "PostSlug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"user.go:5: Posts    []*Post `json:\"posts,omitempty\" gorm:\"foreignKey:AuthorId\"`",
+		"user.go:7: Comments []*Post",
+		"user.go:14: Post     *Post `gorm:\"foreignKey:PostSlug;references:Slug\"`",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got references:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/base-go/cmd/version"
+)
+
+// SnapshotDir holds a copy of every generated file as it was generated, mirroring the project
+// layout. base regen uses the copy as the common ancestor when merging newer templates.
+var SnapshotDir = filepath.Join(".base", "generated")
+
+// generatedHeaderPrefix starts the comment recording how a file was generated
+const generatedHeaderPrefix = "// Generated by base "
+
+// GeneratedInfo is what the header of a generated file records: the generator version and
+// the model and field definitions it was run with
+type GeneratedInfo struct {
+	Version string
+	Model   string
+	Fields  []string
+}
+
+// Header returns the comment line recording the info, e.g.
+// "// Generated by base 2.1.0: Post title:string author:belongsTo:User"
+func (info GeneratedInfo) Header() string {
+	spec := []string{info.Model}
+	for _, field := range info.Fields {
+		// Definitions from a schema may contain spaces, e.g. in a default value
+		if strings.ContainsAny(field, " \t\"") {
+			field = strconv.Quote(field)
+		}
+		spec = append(spec, field)
+	}
+	return fmt.Sprintf("%s%s: %s", generatedHeaderPrefix, info.Version, strings.Join(spec, " "))
+}
+
+// NewGeneratedInfo records a generation by the running version of the CLI
+func NewGeneratedInfo(model string, fields []string) GeneratedInfo {
+	return GeneratedInfo{Version: version.Version, Model: model, Fields: fields}
+}
+
+// AddGeneratedHeader puts the header of info above a generated Go file, separated by a blank
+// line so it does not become the package comment
+func AddGeneratedHeader(content []byte, info GeneratedInfo) []byte {
+	return append([]byte(info.Header()+"\n\n"), content...)
+}
+
+// ReadGeneratedHeader returns the info recorded in the header of a generated file
+func ReadGeneratedHeader(content []byte) (GeneratedInfo, bool) {
+	line, _, _ := bytes.Cut(content, []byte("\n"))
+	rest, ok := strings.CutPrefix(strings.TrimSpace(string(line)), generatedHeaderPrefix)
+	if !ok {
+		return GeneratedInfo{}, false
+	}
+	ver, spec, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return GeneratedInfo{}, false
+	}
+	words, err := splitSpec(spec)
+	if err != nil || len(words) == 0 {
+		return GeneratedInfo{}, false
+	}
+	return GeneratedInfo{Version: ver, Model: words[0], Fields: words[1:]}, true
+}
+
+// SetGeneratedHeader replaces the header of a generated file. Files without a header are
+// returned unchanged.
+func SetGeneratedHeader(content []byte, info GeneratedInfo) []byte {
+	if _, ok := ReadGeneratedHeader(content); !ok {
+		return content
+	}
+	_, rest, _ := bytes.Cut(content, []byte("\n"))
+	return append([]byte(info.Header()+"\n"), rest...)
+}
+
+// splitSpec splits a header spec on spaces, unquoting Go-quoted words
+func splitSpec(spec string) ([]string, error) {
+	var words []string
+	for spec = strings.TrimSpace(spec); spec != ""; spec = strings.TrimSpace(spec) {
+		if spec[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(spec)
+			if err != nil {
+				return nil, err
+			}
+			word, _ := strconv.Unquote(quoted)
+			words = append(words, word)
+			spec = spec[len(quoted):]
+			continue
+		}
+		word, rest, _ := strings.Cut(spec, " ")
+		words = append(words, word)
+		spec = rest
+	}
+	return words, nil
+}
+
+// SnapshotPath returns where the snapshot of a generated file is kept
+func SnapshotPath(path string) string {
+	return filepath.Join(SnapshotDir, filepath.Clean(path))
+}
+
+// LoadSnapshot returns the content a file had when it was last generated, or nil when no
+// snapshot exists
+func LoadSnapshot(path string) ([]byte, error) {
+	content, err := os.ReadFile(SnapshotPath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot of %s: %w", path, err)
+	}
+	return content, nil
+}
+
+// SaveSnapshot records the content a file was generated with
+func SaveSnapshot(path string, content []byte) error {
+	snapshot := SnapshotPath(path)
+	if err := os.MkdirAll(filepath.Dir(snapshot), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", snapshot, err)
+	}
+	if err := os.WriteFile(snapshot, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", snapshot, err)
+	}
+	return nil
+}
+
+// SaveSnapshots records the generated files of a change set. Edits and files written
+// alongside an existing one (.new) are not generated output of their path and are skipped.
+func SaveSnapshots(changes *ChangeSet) error {
+	for _, change := range changes.Changes {
+		if change.Template == "" || change.Edit || strings.HasSuffix(change.Path, ".new") {
+			continue
+		}
+		if err := SaveSnapshot(change.Path, change.After); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSnapshot removes the snapshot of a file or directory, together with directories left empty
+func RemoveSnapshot(path string) error {
+	snapshot := SnapshotPath(path)
+	if err := os.RemoveAll(snapshot); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", snapshot, err)
+	}
+	removeEmptyDirs(filepath.Dir(snapshot))
+	return nil
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// DefaultModulePath is the module path of the Base framework as downloaded
+const DefaultModulePath = "base"
+
+// ModulePath returns the module path declared in the project's go.mod, or DefaultModulePath
+// when there is no go.mod or it cannot be read
+func ModulePath() string {
+	if path, err := ReadModulePath("."); err == nil {
+		return path
+	}
+	return DefaultModulePath
+}
+
+// ReadModulePath returns the module path declared in dir/go.mod
+func ReadModulePath(dir string) (string, error) {
+	modPath := filepath.Join(dir, "go.mod")
+	content, err := os.ReadFile(modPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", modPath, err)
+	}
+	path := modfile.ModulePath(content)
+	if path == "" {
+		return "", fmt.Errorf("no module declaration in %s", modPath)
+	}
+	return path, nil
+}
+
+// RewriteModulePath changes the module path of the project in dir from oldPath to newPath,
+// in go.mod and in the imports of every Go file. It returns the number of Go files changed.
+func RewriteModulePath(dir, oldPath, newPath string) (int, error) {
+	modPath := filepath.Join(dir, "go.mod")
+	content, err := os.ReadFile(modPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", modPath, err)
+	}
+	modFile, err := modfile.Parse(modPath, content, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", modPath, err)
+	}
+	if err := modFile.AddModuleStmt(newPath); err != nil {
+		return 0, fmt.Errorf("failed to set module path: %w", err)
+	}
+	updated, err := modFile.Format()
+	if err != nil {
+		return 0, fmt.Errorf("failed to format %s: %w", modPath, err)
+	}
+	if err := os.WriteFile(modPath, updated, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", modPath, err)
+	}
+
+	changed := 0
+	err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if name := entry.Name(); path != dir && (name == "vendor" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		rewritten, err := rewriteImports(path, src, oldPath, newPath)
+		if err != nil {
+			return err
+		}
+		if rewritten == nil {
+			return nil
+		}
+		if err := os.WriteFile(path, rewritten, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		changed++
+		return nil
+	})
+	return changed, err
+}
+
+// rewriteImports replaces the oldPath prefix of import paths in a Go file. It returns nil when
+// nothing was imported from oldPath. Only the import literals change, so formatting is kept.
+func rewriteImports(filename string, src []byte, oldPath, newPath string) ([]byte, error) {
+	source, err := parseGoSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []sourceEdit
+	for _, imp := range source.file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || (importPath != oldPath && !strings.HasPrefix(importPath, oldPath+"/")) {
+			continue
+		}
+		edits = append(edits, sourceEdit{
+			start: source.offset(imp.Path.Pos()),
+			end:   source.offset(imp.Path.End()),
+			text:  strconv.Quote(newPath + strings.TrimPrefix(importPath, oldPath)),
+		})
+	}
+	if len(edits) == 0 {
+		return nil, nil
+	}
+
+	// The new paths may sort differently, gofmt puts them back in order
+	updated := applyEdits(src, edits)
+	if formatted, err := format.Source(updated); err == nil {
+		return formatted, nil
+	}
+	return updated, nil
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"archive/zip"
-	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -174,119 +173,6 @@ func TrimIdSuffix(s string) string {
 	return s
 }
 
-func UpdateInitFile(singularName, pluralName string) error {
-	initFilePath := "app/init.go"
-
-	content, err := os.ReadFile(initFilePath)
-	if err != nil {
-		return err
-	}
-
-	packageName := ToSnakeCase(singularName)
-
-	importStr := fmt.Sprintf("\"base/app/%s\"", ToSnakeCase(pluralName))
-	content, importAdded := AddImport(content, importStr)
-
-	content, initializerAdded := AddModuleInitializer(content, packageName, singularName)
-
-	if importAdded || initializerAdded {
-		return os.WriteFile(initFilePath, content, 0644)
-	}
-
-	return nil
-}
-
-func AddModuleInitializer(content []byte, packageName, singularName string) ([]byte, bool) {
-	contentStr := string(content)
-
-	// Find the return modules line to add before it
-	returnIndex := strings.Index(contentStr, "return modules")
-	if returnIndex == -1 {
-		return content, false
-	}
-
-	if strings.Contains(contentStr[:returnIndex], fmt.Sprintf(`modules["%s"]`, packageName)) {
-		return content, false
-	}
-
-	structName := ToPascalCase(singularName)
-
-	newInitializer := fmt.Sprintf(`	modules["%s"] = %s.New%sModule(deps.DB)
-`,
-		packageName, packageName, structName)
-
-	// Find the line before return modules
-	lineStart := strings.LastIndex(contentStr[:returnIndex], "\n") + 1
-
-	updatedContent := contentStr[:lineStart] + newInitializer + contentStr[lineStart:]
-
-	return []byte(updatedContent), true
-}
-
-func RemoveImport(content []byte, importStr string) []byte {
-	contentStr := string(content)
-	importIndex := strings.Index(contentStr, importStr)
-	if importIndex != -1 {
-		// Find the start of the line
-		lineStart := strings.LastIndex(contentStr[:importIndex], "\n") + 1
-		// Find the end of the line
-		lineEnd := strings.Index(contentStr[importIndex:], "\n") + importIndex
-		if lineEnd == -1 {
-			lineEnd = len(contentStr)
-		}
-		// Remove the line
-		contentStr = contentStr[:lineStart] + contentStr[lineEnd:]
-	}
-	return []byte(contentStr)
-}
-
-func RemoveModuleInitializer(content []byte, pluralName string) []byte {
-	contentStr := string(content)
-	// Look for the module initializer pattern: modules["pluralName"] = pluralName.Init(deps)
-	moduleStr := fmt.Sprintf(`modules["%s"]`, pluralName)
-	initializerStart := strings.Index(contentStr, moduleStr)
-	if initializerStart != -1 {
-		// Find the start of the line (including any comment above)
-		lineStart := strings.LastIndex(contentStr[:initializerStart], "\n") + 1
-
-		// Check if there's a comment line above this module
-		if lineStart > 1 {
-			prevLineStart := strings.LastIndex(contentStr[:lineStart-1], "\n") + 1
-			prevLine := strings.TrimSpace(contentStr[prevLineStart:lineStart])
-			if strings.HasPrefix(prevLine, "//") && strings.Contains(prevLine, "module") {
-				lineStart = prevLineStart // Include the comment line
-			}
-		}
-
-		// Find the end of the line
-		initializerEnd := strings.Index(contentStr[initializerStart:], "\n") + initializerStart
-		if initializerEnd != -1 {
-			initializerEnd++ // Include the newline
-			contentStr = contentStr[:lineStart] + contentStr[initializerEnd:]
-		}
-	}
-	return []byte(contentStr)
-}
-
-func AddImport(content []byte, importStr string) ([]byte, bool) {
-	if bytes.Contains(content, []byte(importStr)) {
-		return content, false
-	}
-
-	importPos := bytes.Index(content, []byte("import ("))
-	if importPos == -1 {
-		return content, false
-	}
-
-	insertPos := importPos + len("import (") + 1
-
-	newImportLine := []byte("\t" + importStr + "\n")
-
-	updatedContent := append(content[:insertPos], append(newImportLine, content[insertPos:]...)...)
-
-	return updatedContent, true
-}
-
 func GetRequiredImports(fields []Field) map[string][]string {
 	modelImports := []string{
 		"time",
@@ -302,7 +188,7 @@ func GetRequiredImports(fields []Field) map[string][]string {
 	if HasFieldType(fields, "*storage.File") {
 		modelImports = append(modelImports,
 			"gorm.io/gorm",
-			"base/core/storage",
+			ModulePath()+"/core/storage",
 		)
 		serviceImports = append(serviceImports,
 			"mime/multipart",
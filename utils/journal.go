@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JournalPath is the file recording every operation that changed the project, one JSON entry per line
+var JournalPath = filepath.Join(".base", "journal")
+
+// Journal file actions
+const (
+	JournalCreated  = "created"
+	JournalModified = "modified"
+	JournalDeleted  = "deleted"
+)
+
+// JournalEntry records the files changed by one command
+type JournalEntry struct {
+	Time    time.Time     `json:"time"`
+	Command string        `json:"command"`
+	Files   []JournalFile `json:"files"`
+}
+
+// JournalFile records how one file changed. Before holds the content of modified and deleted
+// files so they can be restored; AfterHash identifies the content written, so later edits can
+// be detected.
+type JournalFile struct {
+	Path      string `json:"path"`
+	Action    string `json:"action"`
+	Before    []byte `json:"before,omitempty"`
+	AfterHash string `json:"after_hash,omitempty"`
+}
+
+// Summary counts the files of the entry by action, e.g. "3 created, 1 modified"
+func (e *JournalEntry) Summary() string {
+	counts := make(map[string]int)
+	for _, file := range e.Files {
+		counts[file.Action]++
+	}
+
+	var parts []string
+	for _, action := range []string{JournalCreated, JournalModified, JournalDeleted} {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// hashContent returns the hex SHA-256 of content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// JournalRecorder snapshots files before a command changes them and records the difference
+// afterwards. Files that end up unchanged are not recorded.
+type JournalRecorder struct {
+	command string
+	before  map[string][]byte // nil content means the file did not exist
+	dirs    []string
+}
+
+// NewJournalRecorder starts recording the changes made by command
+func NewJournalRecorder(command string) *JournalRecorder {
+	return &JournalRecorder{command: command, before: make(map[string][]byte)}
+}
+
+// Track snapshots files that the command may create, modify or delete
+func (r *JournalRecorder) Track(paths ...string) {
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if _, ok := r.before[path]; ok {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			content = nil
+		} else if content == nil {
+			content = []byte{}
+		}
+		r.before[path] = content
+	}
+}
+
+// TrackDir snapshots every file below dir. Files created below it are recorded as well.
+func (r *JournalRecorder) TrackDir(dir string) {
+	r.dirs = append(r.dirs, filepath.Clean(dir))
+	r.Track(filesBelow(dir)...)
+}
+
+// filesBelow lists the regular files below dir, which need not exist
+func filesBelow(dir string) []string {
+	var files []string
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// Commit compares the tracked files with their snapshots and appends an entry for the
+// changes to the journal. It returns nil when nothing changed.
+func (r *JournalRecorder) Commit() (*JournalEntry, error) {
+	for _, dir := range r.dirs {
+		for _, path := range filesBelow(dir) {
+			if _, ok := r.before[path]; !ok {
+				r.before[path] = nil
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(r.before))
+	for path := range r.before {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	entry := &JournalEntry{Time: time.Now(), Command: r.command}
+	for _, path := range paths {
+		before := r.before[path]
+		after, err := os.ReadFile(path)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		switch {
+		case before == nil && exists:
+			entry.Files = append(entry.Files, JournalFile{Path: path, Action: JournalCreated, AfterHash: hashContent(after)})
+		case before != nil && !exists:
+			entry.Files = append(entry.Files, JournalFile{Path: path, Action: JournalDeleted, Before: before})
+		case before != nil && !bytes.Equal(before, after):
+			entry.Files = append(entry.Files, JournalFile{Path: path, Action: JournalModified, Before: before, AfterHash: hashContent(after)})
+		}
+	}
+
+	if len(entry.Files) == 0 {
+		return nil, nil
+	}
+	if err := AppendJournal(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// AppendJournal adds an entry to the end of the journal
+func AppendJournal(entry *JournalEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(JournalPath), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(JournalPath), err)
+	}
+	file, err := os.OpenFile(JournalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", JournalPath, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", JournalPath, err)
+	}
+	return nil
+}
+
+// LoadJournal returns every journal entry, oldest first. A missing journal is empty.
+func LoadJournal() ([]*JournalEntry, error) {
+	file, err := os.Open(JournalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", JournalPath, err)
+	}
+	defer file.Close()
+
+	var entries []*JournalEntry
+	scanner := bufio.NewScanner(file)
+	// Entries hold whole files, so lines can be far longer than the default limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid entry: %w", JournalPath, line, err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", JournalPath, err)
+	}
+	return entries, nil
+}
+
+// saveJournal rewrites the journal with the given entries
+func saveJournal(entries []*JournalEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(JournalPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", JournalPath, err)
+	}
+	return nil
+}
+
+// EditedSince lists the files of an entry whose current state no longer matches what the
+// command left behind
+func (e *JournalEntry) EditedSince() []string {
+	var edited []string
+	for _, file := range e.Files {
+		content, err := os.ReadFile(file.Path)
+		exists := err == nil
+
+		switch file.Action {
+		case JournalDeleted:
+			if exists {
+				edited = append(edited, file.Path+" was recreated")
+			}
+		default:
+			if !exists {
+				edited = append(edited, file.Path+" was deleted")
+			} else if hashContent(content) != file.AfterHash {
+				edited = append(edited, file.Path+" was edited")
+			}
+		}
+	}
+	return edited
+}
+
+// Revert restores the files of an entry to their state before the command ran. Created
+// files are removed, together with directories left empty. Unless force is set, nothing is
+// reverted when files changed since the command ran.
+func (e *JournalEntry) Revert(force bool) error {
+	if edited := e.EditedSince(); len(edited) > 0 && !force {
+		return fmt.Errorf("files changed since the operation: %s", strings.Join(edited, ", "))
+	}
+
+	for _, file := range e.Files {
+		switch file.Action {
+		case JournalCreated:
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+			removeEmptyDirs(filepath.Dir(file.Path))
+		default:
+			if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+			}
+			if err := os.WriteFile(file.Path, file.Before, 0644); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents as long as they are empty
+func removeEmptyDirs(dir string) {
+	// os.Remove fails on directories that are not empty, which ends the walk up
+	for ; dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// DropLastJournalEntry removes the newest entry from the journal
+func DropLastJournalEntry() error {
+	entries, err := LoadJournal()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return saveJournal(entries[:len(entries)-1])
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFiles writes files relative to the current directory, creating their directories
+func writeFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recordOperation runs a command in a fresh project with the given files and returns its
+// journal entry
+func recordOperation(t *testing.T, files map[string]string, operation func()) *JournalEntry {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	writeFiles(t, files)
+
+	recorder := NewJournalRecorder("base g Post title:string")
+	recorder.Track("app/init.go", "app/old.go")
+	recorder.TrackDir("app/posts")
+	operation()
+	entry, err := recorder.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil {
+		t.Fatal("no changes recorded")
+	}
+	return entry
+}
+
+// generatePosts creates a module, registers it and deletes an old file
+func generatePosts() {
+	os.MkdirAll("app/posts", 0755)
+	os.WriteFile("app/posts/service.go", []byte("package posts\n"), 0644)
+	os.WriteFile("app/init.go", []byte("package app // with posts\n"), 0644)
+	os.Remove("app/old.go")
+}
+
+func TestJournalRevert(t *testing.T) {
+	entry := recordOperation(t, map[string]string{
+		"app/init.go": "package app\n",
+		"app/old.go":  "package app // old\n",
+	}, generatePosts)
+
+	if got, want := entry.Summary(), "1 created, 1 modified, 1 deleted"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if edited := entry.EditedSince(); len(edited) > 0 {
+		t.Fatalf("EditedSince() = %q right after the operation", edited)
+	}
+
+	if err := entry.Revert(false); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{"app/init.go": "package app\n", "app/old.go": "package app // old\n"} {
+		if got, err := os.ReadFile(path); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", path, got, err, want)
+		}
+	}
+	if _, err := os.Stat("app/posts"); !os.IsNotExist(err) {
+		t.Errorf("app/posts was not removed: %v", err)
+	}
+}
+
+func TestJournalEditedSince(t *testing.T) {
+	entry := recordOperation(t, map[string]string{
+		"app/init.go": "package app\n",
+		"app/old.go":  "package app // old\n",
+	}, generatePosts)
+
+	writeFiles(t, map[string]string{
+		"app/init.go": "package app // edited by hand\n",
+		"app/old.go":  "package app // recreated\n",
+	})
+	os.Remove("app/posts/service.go")
+
+	want := []string{"app/init.go was edited", "app/old.go was recreated", "app/posts/service.go was deleted"}
+	got := entry.EditedSince()
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("EditedSince() = %q, want %q", got, want)
+	}
+
+	// Undo refuses to discard the later changes unless forced
+	if err := entry.Revert(false); err == nil {
+		t.Fatal("Revert(false) succeeded although files changed")
+	}
+	if got, _ := os.ReadFile("app/init.go"); string(got) != "package app // edited by hand\n" {
+		t.Errorf("app/init.go = %q after a refused revert", got)
+	}
+	if _, err := os.Stat("app/old.go"); err != nil {
+		t.Errorf("app/old.go is gone after a refused revert: %v", err)
+	}
+
+	if err := entry.Revert(true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile("app/init.go"); string(got) != "package app\n" {
+		t.Errorf("app/init.go = %q after a forced revert", got)
+	}
+}
+
+func TestJournalEntries(t *testing.T) {
+	entry := recordOperation(t, map[string]string{"app/init.go": "package app\n"}, generatePosts)
+
+	entries, err := LoadJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != entry.Command || len(entries[0].Files) != len(entry.Files) {
+		t.Fatalf("LoadJournal() = %+v, want the recorded entry", entries)
+	}
+
+	// Nothing changed, so nothing is recorded
+	recorder := NewJournalRecorder("base g Post title:string")
+	recorder.TrackDir("app")
+	if entry, err := recorder.Commit(); err != nil || entry != nil {
+		t.Errorf("Commit() = %+v, %v without changes", entry, err)
+	}
+
+	if err := DropLastJournalEntry(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := LoadJournal(); err != nil || len(entries) != 0 {
+		t.Errorf("LoadJournal() = %d entries, %v after dropping the last one", len(entries), err)
+	}
+}
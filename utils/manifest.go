@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateManifestFile is the name of the manifest listing extra templates in the override directory
+const TemplateManifestFile = "manifest.yaml"
+
+// TemplateManifest lists project templates that generate additional files for every module
+type TemplateManifest struct {
+	Templates []ManifestTemplate `yaml:"templates"`
+}
+
+// ManifestTemplate maps a template to the path of the file it generates.
+// Output is a template itself, e.g. "app/{{.DirName}}/repository.go".
+type ManifestTemplate struct {
+	Template string `yaml:"template"`
+	Output   string `yaml:"output"`
+}
+
+// TemplateManifestPath returns the location of the manifest in the current project
+func TemplateManifestPath() string {
+	return filepath.Join(TemplateOverrideDir, TemplateManifestFile)
+}
+
+// LoadTemplateManifest reads the project's template manifest. A missing manifest is
+// not an error and yields an empty manifest.
+func LoadTemplateManifest() (*TemplateManifest, error) {
+	manifestPath := TemplateManifestPath()
+	content, err := os.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return &TemplateManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestPath, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var manifest TemplateManifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestPath, err)
+	}
+
+	for i, entry := range manifest.Templates {
+		if entry.Template == "" || entry.Output == "" {
+			return nil, fmt.Errorf("invalid %s: entry #%d needs both template and output", manifestPath, i+1)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// OutputPath renders the output pattern for a module, using the same data as the templates
+func (t ManifestTemplate) OutputPath(naming *NamingConvention, fields []Field) (string, error) {
+	output, err := executeTemplate(t.Template+" output", TemplateManifestPath(), t.Output, naming, fields)
+	if err != nil {
+		return "", err
+	}
+
+	outputPath := strings.TrimSpace(string(output))
+	if outputPath == "" {
+		return "", fmt.Errorf("output path for %s is empty", t.Template)
+	}
+	if filepath.IsAbs(outputPath) || strings.HasPrefix(filepath.Clean(outputPath), "..") {
+		return "", fmt.Errorf("output path %s for %s must stay inside the project", outputPath, t.Template)
+	}
+	return filepath.Clean(outputPath), nil
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+)
+
+// MergeLabels name the sides of a conflict in the markers written by Merge3
+type MergeLabels struct {
+	Ours   string
+	Theirs string
+}
+
+// matchLines maps each line of a to the index of the line it is kept as in b, or -1
+func matchLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	i, j := 0, 0
+	for _, op := range diffLines(a, b) {
+		switch op.kind {
+		case ' ':
+			matches[i] = j
+			i++
+			j++
+		case '-':
+			matches[i] = -1
+			i++
+		case '+':
+			j++
+		}
+	}
+	return matches
+}
+
+// Merge3 merges the changes from base to ours and from base to theirs line by line. Regions
+// changed on one side only take that side; regions changed differently on both sides are
+// written between conflict markers. A nil base means the common ancestor is unknown, so every
+// region where ours and theirs differ is a conflict. It returns the merged content and the
+// number of conflicts.
+func Merge3(base, ours, theirs []byte, labels MergeLabels) ([]byte, int) {
+	oursLines := splitLines(string(ours))
+	theirsLines := splitLines(string(theirs))
+
+	var out []string
+	conflicts := 0
+	conflict := func(o, t []string) {
+		out = append(out, "<<<<<<< "+labels.Ours)
+		out = append(out, o...)
+		out = append(out, "=======")
+		out = append(out, t...)
+		out = append(out, ">>>>>>> "+labels.Theirs)
+		conflicts++
+	}
+
+	if base == nil {
+		// Without a base, lines both sides share are kept and everything else conflicts
+		var o, t []string
+		flush := func() {
+			if len(o) > 0 || len(t) > 0 {
+				conflict(o, t)
+			}
+			o, t = nil, nil
+		}
+		for _, op := range diffLines(oursLines, theirsLines) {
+			switch op.kind {
+			case ' ':
+				flush()
+				out = append(out, op.line)
+			case '-':
+				o = append(o, op.line)
+			case '+':
+				t = append(t, op.line)
+			}
+		}
+		flush()
+		return joinLines(out), conflicts
+	}
+
+	baseLines := splitLines(string(base))
+	inOurs := matchLines(baseLines, oursLines)
+	inTheirs := matchLines(baseLines, theirsLines)
+
+	i, j, k := 0, 0, 0
+	for i < len(baseLines) || j < len(oursLines) || k < len(theirsLines) {
+		// A base line kept at the current position on both sides is stable
+		if i < len(baseLines) && inOurs[i] == j && inTheirs[i] == k {
+			out = append(out, baseLines[i])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// Otherwise the unstable chunk runs up to the next base line kept on both sides
+		next := i
+		for next < len(baseLines) && (inOurs[next] < 0 || inTheirs[next] < 0) {
+			next++
+		}
+		nextOurs, nextTheirs := len(oursLines), len(theirsLines)
+		if next < len(baseLines) {
+			nextOurs, nextTheirs = inOurs[next], inTheirs[next]
+		}
+
+		b, o, t := baseLines[i:next], oursLines[j:nextOurs], theirsLines[k:nextTheirs]
+		switch {
+		case slices.Equal(o, b):
+			out = append(out, t...)
+		case slices.Equal(t, b), slices.Equal(o, t):
+			out = append(out, o...)
+		default:
+			conflict(o, t)
+		}
+		i, j, k = next, nextOurs, nextTheirs
+	}
+	return joinLines(out), conflicts
+}
+
+// joinLines joins lines into content ending with a newline
+func joinLines(lines []string) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
@@ -0,0 +1,137 @@
+package utils
+
+import "testing"
+
+func TestMerge3(t *testing.T) {
+	labels := MergeLabels{Ours: "current", Theirs: "new"}
+	tests := []struct {
+		name      string
+		base      []byte
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "unchanged",
+			base:   []byte("a\nb\nc\n"),
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "change on our side only",
+			base:   []byte("a\nb\nc\n"),
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "change on their side only",
+			base:   []byte("a\nb\nc\n"),
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   "a\nb\nC\n",
+		},
+		{
+			name:   "changes on both sides in different places",
+			base:   []byte("a\nb\nc\nd\ne\n"),
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   []byte("a\nb\nc\n"),
+			ours:   "a\nB\nc\n",
+			theirs: "a\nB\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:      "conflicting change",
+			base:      []byte("a\nb\nc\n"),
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\n<<<<<<< current\nours\n=======\ntheirs\n>>>>>>> new\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "deleted on one side, changed on the other",
+			base:      []byte("a\nb\nc\n"),
+			ours:      "a\nc\n",
+			theirs:    "a\nB\nc\n",
+			want:      "a\n<<<<<<< current\n=======\nB\n>>>>>>> new\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "nil base keeps shared lines and conflicts on the rest",
+			base:      nil,
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\nd\n",
+			want:      "a\n<<<<<<< current\nours\n=======\ntheirs\n>>>>>>> new\nc\n<<<<<<< current\n=======\nd\n>>>>>>> new\n",
+			conflicts: 2,
+		},
+		{
+			name:   "nil base with equal sides",
+			base:   nil,
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:   "insertions on both sides around a stable line",
+			base:   []byte("a\nb\n"),
+			ours:   "a\nx\nb\n",
+			theirs: "a\nb\ny\n",
+			want:   "a\nx\nb\ny\n",
+		},
+		{
+			name:      "different insertions at the same place",
+			base:      []byte("a\nb\n"),
+			ours:      "a\nx\nb\n",
+			theirs:    "a\ny\nb\n",
+			want:      "a\n<<<<<<< current\nx\n=======\ny\n>>>>>>> new\nb\n",
+			conflicts: 1,
+		},
+		{
+			name:   "same insertion at the same place",
+			base:   []byte("a\nb\n"),
+			ours:   "a\nx\nb\n",
+			theirs: "a\nx\nb\n",
+			want:   "a\nx\nb\n",
+		},
+		{
+			name:   "no trailing newline",
+			base:   []byte("a\nb"),
+			ours:   "a\nB",
+			theirs: "a\nb",
+			want:   "a\nB\n",
+		},
+		{
+			name:   "trailing newline added on one side only",
+			base:   []byte("a\nb"),
+			ours:   "a\nb",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:   "empty base",
+			base:   []byte{},
+			ours:   "",
+			theirs: "a\n",
+			want:   "a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflicts := Merge3(tt.base, []byte(tt.ours), []byte(tt.theirs), labels)
+			if string(got) != tt.want {
+				t.Errorf("merged content:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if conflicts != tt.conflicts {
+				t.Errorf("got %d conflicts, want %d", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package utils
 
 import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"unicode"
 )
@@ -109,11 +114,35 @@ type Field struct {
 
 	// For relations
 	IsRelation   bool
-	RelationType string // belongs_to, has_many, has_one, many_to_many
+	RelationType string     // belongs_to, has_many, has_one, many_to_many, polymorphic
+	IsTree       bool       // belongs_to the model itself, with a nullable key and a Children collection
+	Through      *JoinModel // Join model of a many_to_many relation with fields of its own
+
+	// Polymorphic relations, e.g. commentable:polymorphic:Post,Photo on Comment
+	Polymorphic       string   // Relation name (Commentable) on its Id/Type columns and on the owners' hasMany
+	PolymorphicOwners []string // Models the Type column accepts, any when empty
 
 	// Validation
-	IsRequired bool
-	IsUnique   bool
+	IsRequired  bool
+	IsUnique    bool
+	IsIndex     bool
+	Size        int    // Maximum column length (0 when not set)
+	Default     string // Column default value
+	ValidateTag string // Validator rules for the create request (e.g. "required,max=200")
+
+	// Enums, e.g. status:enum(draft,published). NewTemplateData names the type after the model (PostStatus).
+	EnumValues []string // Valid values, the first one being the default
+
+	// State machines, e.g. status:state(draft->review->published), are enums whose values only
+	// change through the listed transitions. NewTemplateData keeps them on the first one only.
+	Transitions []StateTransition
+
+	// Secrets (password, secret) are stored as bcrypt hashes and left out of responses
+	IsSecret bool
+
+	// Format is the alias of a string with a format of its own (email, url, phone or slug),
+	// which the create and update validators check
+	Format string
 
 	// Special types
 	IsImage      bool
@@ -121,9 +150,136 @@ type Field struct {
 	IsAttachment bool
 }
 
-// ParseField creates a properly structured Field from a field definition string
-func ParseField(fieldDef string) Field {
-	parts := strings.Split(fieldDef, ":")
+// JoinModel is the model linking the two sides of a many-to-many relation when the link has
+// fields of its own, e.g. Membership in members:manyToMany:User:through=Membership(role:string)
+type JoinModel struct {
+	Name       string  // Go type of the join model
+	TableName  string  // Join table, also named by the many2many tag
+	Link       string  // Singular of the relation field, naming the link methods (AddMember)
+	OwnerKey   string  // Key field pointing at the model declaring the relation
+	RelatedKey string  // Key field pointing at the related model
+	Fields     []Field // Fields of the link besides the keys
+}
+
+// StateTransition lists the states a state machine field can move to from one state
+type StateTransition struct {
+	From string
+	To   []string
+}
+
+// TransitionsFrom returns the states a state machine field can move to from state
+func (f Field) TransitionsFrom(state string) []string {
+	for _, transition := range f.Transitions {
+		if transition.From == state {
+			return transition.To
+		}
+	}
+	return nil
+}
+
+// CanTransition reports whether a state machine field can move from one state to another
+func (f Field) CanTransition(from, to string) bool {
+	return slices.Contains(f.TransitionsFrom(from), to)
+}
+
+// StateEvents returns the states transitions lead to, in the order of the values. Moving into
+// one of them is a transition event, e.g. "published".
+func (f Field) StateEvents() []string {
+	var events []string
+	for _, state := range f.EnumValues {
+		if slices.ContainsFunc(f.Transitions, func(t StateTransition) bool { return slices.Contains(t.To, state) }) {
+			events = append(events, state)
+		}
+	}
+	return events
+}
+
+// UpdateValidateTag returns the validator rules of the update request, in which every field is
+// optional: the create rules without required, applied to the values that are present
+func (f Field) UpdateValidateTag() string {
+	rules := slices.DeleteFunc(strings.Split(f.ValidateTag, ","), func(rule string) bool {
+		return rule == "" || rule == "required"
+	})
+	if len(rules) == 0 {
+		return ""
+	}
+	return "omitempty," + strings.Join(rules, ",")
+}
+
+// ParseField creates a properly structured Field from a field definition string.
+// A trailing modifier segment is supported, e.g. "title:string:required,unique,size=200".
+func ParseField(fieldDef string) (Field, error) {
+	parts, modifiers := splitFieldModifiers(splitOutsideParens(fieldDef, ':'))
+	field := parseFieldParts(parts)
+	if err := applyFieldModifiers(&field, modifiers); err != nil {
+		return Field{}, err
+	}
+	setTestValues(&field)
+	return field, nil
+}
+
+// setTestValues fills in the Go expressions used by the test templates. Fields without a
+// test value (attachments and relation objects) are left out of generated requests.
+// TestValueWithIndex uses the loop variable i; TestValueUnique calls nextSeq(), which the
+// generated test helpers provide.
+func setTestValues(field *Field) {
+	if len(field.Transitions) > 0 {
+		// State machines start in their default state and only change through transitions
+		return
+	}
+	if len(field.EnumValues) > 0 {
+		values := field.EnumValues
+		field.TestValue, field.TestValueWithIndex, field.TestValueUnique = strconv.Quote(values[0]), strconv.Quote(values[0]), strconv.Quote(values[0])
+		field.UpdateTestValue = strconv.Quote(values[len(values)-1])
+		return
+	}
+
+	testValue := func(index string) (string, string, string) {
+		switch field.Type {
+		case "string", "translation.Field":
+			lower := strings.ToLower(field.Name)
+			switch {
+			case field.Format == "phone":
+				return `"+14155550100"`, `"+14155550199"`, fmt.Sprintf(`fmt.Sprintf("+1415555%%04d", %s)`, index)
+			case field.Format == "slug":
+				return `"test-slug"`, `"updated-slug"`, fmt.Sprintf(`fmt.Sprintf("test-slug-%%d", %s)`, index)
+			case field.Format == "email" || strings.Contains(lower, "email"):
+				return `"test@example.com"`, `"updated@example.com"`, fmt.Sprintf(`fmt.Sprintf("test%%d@example.com", %s)`, index)
+			case field.Format == "url" || strings.Contains(lower, "url") || strings.Contains(lower, "link"):
+				return `"https://example.com/test"`, `"https://example.com/updated"`, fmt.Sprintf(`fmt.Sprintf("https://example.com/test-%%d", %s)`, index)
+			case field.Size > 0 && field.Size < 24:
+				// Keep values within short column sizes
+				return `"t"`, `"u"`, fmt.Sprintf(`fmt.Sprint(%s %% 10)`, index)
+			}
+			return fmt.Sprintf(`"Test %s"`, field.Name), fmt.Sprintf(`"Updated %s"`, field.Name),
+				fmt.Sprintf(`fmt.Sprintf("Test %s %%d", %s)`, field.Name, index)
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return "1", "2", fmt.Sprintf("%s(%s + 1)", field.Type, index)
+		case "float32", "float64":
+			return "1.5", "2.5", fmt.Sprintf("%s(%s) + 0.5", field.Type, index)
+		case "bool":
+			return "true", "false", fmt.Sprintf("%s%%2 == 0", index)
+		case "types.DateTime":
+			return "types.DateTime{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}",
+				"types.DateTime{Time: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)}",
+				fmt.Sprintf("types.DateTime{Time: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, %s)}", index)
+		case "time.Time":
+			return "time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)",
+				"time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)",
+				fmt.Sprintf("time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, %s)", index)
+		}
+		return "", "", ""
+	}
+
+	if field.IsAttachment || (field.IsRelation && field.RelationType != "belongs_to") {
+		return
+	}
+	field.TestValue, field.UpdateTestValue, field.TestValueWithIndex = testValue("i")
+	_, _, field.TestValueUnique = testValue("nextSeq()")
+}
+
+// parseFieldParts builds a Field from the name, type and related model parts of a definition
+func parseFieldParts(parts []string) Field {
 	fieldName := parts[0]
 	var fieldType string
 
@@ -157,9 +313,15 @@ func ParseField(fieldDef string) Field {
 			return parseHasOneField(fieldName, parts, field)
 		case "many_to_many":
 			return parseManyToManyField(fieldName, parts, field)
+		case "polymorphic":
+			return parsePolymorphicField(fieldName, parts, field)
 		}
 	} else if fieldType == "attachment" || fieldType == "file" || fieldType == "image" {
 		return parseAttachmentField(fieldName, fieldType, field)
+	} else if values, ok := strings.CutPrefix(strings.ToLower(fieldType), "enum("); ok {
+		return parseEnumField(fieldType[len(fieldType)-len(values):], field)
+	} else if chains, ok := strings.CutPrefix(strings.ToLower(fieldType), "state("); ok {
+		return parseStateField(fieldType[len(fieldType)-len(chains):], field)
 	}
 
 	// Handle regular fields using the new alias system
@@ -177,6 +339,10 @@ func ParseField(fieldDef string) Field {
 		field.JSONTag = ToSnakeCase(fieldName)
 		field.JSONName = ToSnakeCase(fieldName)
 		field.GORMTag = `gorm:"foreignKey:ModelId;references:Id"`
+	case "secret":
+		field.IsSecret = true
+	case "format":
+		field.Format = resolved.CanonicalType
 	}
 
 	field.GORM = field.GORMTag
@@ -184,6 +350,251 @@ func ParseField(fieldDef string) Field {
 	return field
 }
 
+// fieldModifiers lists the modifiers accepted in the trailing segment of a field definition
+var fieldModifiers = map[string]bool{
+	"required": true,
+	"unique":   true,
+	"index":    true,
+	"size":     true,
+	"default":  true,
+
+	// Relations pointing back from the related model, e.g. posts:hasMany:Post:foreignKey=author_id
+	"foreignkey":  true,
+	"foreign_key": true,
+
+	// Join models with fields, e.g. members:manyToMany:User:through=Membership(role:string)
+	"through": true,
+
+	// Owners of a polymorphic relation, e.g. comments:hasMany:Comment:polymorphic=commentable
+	"polymorphic": true,
+}
+
+// splitOutsideParens splits s on sep, except inside parentheses, so that the join fields in
+// through=Membership(role:string,level:int) stay in one piece
+func splitOutsideParens(s string, sep rune) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case r == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// isModifierList reports whether every comma-separated entry of s is a known modifier
+func isModifierList(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, mod := range splitOutsideParens(s, ',') {
+		key, _, _ := strings.Cut(strings.TrimSpace(mod), "=")
+		if !fieldModifiers[strings.ToLower(key)] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFieldModifiers separates the trailing modifier segment from the name and type parts.
+// For relationships the third part is the related model, so it is only treated as
+// modifiers when it consists solely of known modifier names.
+func splitFieldModifiers(parts []string) ([]string, []string) {
+	if len(parts) < 3 {
+		return parts, nil
+	}
+
+	last := parts[len(parts)-1]
+	if !IsRelationshipType(parts[1]) || len(parts) > 3 || isModifierList(last) {
+		return parts[:len(parts)-1], splitOutsideParens(last, ',')
+	}
+
+	return parts, nil
+}
+
+// bcryptMaxLength is the longest secret bcrypt hashes, in bytes
+const bcryptMaxLength = 72
+
+// applyFieldModifiers applies parsed modifiers and derives the GORM tag and validation rules.
+// A modifier that is not in fieldModifiers is an error, most likely a typo.
+func applyFieldModifiers(field *Field, modifiers []string) error {
+	for _, mod := range modifiers {
+		if key, _, _ := strings.Cut(strings.TrimSpace(mod), "="); key != "" && !fieldModifiers[strings.ToLower(key)] {
+			return fmt.Errorf("unknown modifier %q on field %s", key, field.Name)
+		}
+	}
+
+	// Enums default to their first value and secrets are limited to what bcrypt hashes, so they
+	// get a GORM tag or validation rules without modifiers
+	if len(modifiers) == 0 && len(field.EnumValues) == 0 && !field.IsSecret {
+		return nil
+	}
+
+	// The columns of a polymorphic relation can only be required
+	if field.RelationType == "polymorphic" {
+		for _, mod := range modifiers {
+			if key, _, _ := strings.Cut(strings.TrimSpace(mod), "="); strings.EqualFold(key, "required") {
+				field.IsRequired = true
+			} else {
+				fmt.Printf("Warning: modifier %q is not supported on relation %s and was ignored\n", key, field.Name)
+			}
+		}
+		return nil
+	}
+
+	// hasMany and hasOne take the foreign key on the related model or the polymorphic relation
+	// it belongs to, manyToMany its join model
+	if field.RelationType == "has_many" || field.RelationType == "has_one" || field.RelationType == "many_to_many" {
+		toMany := field.RelationType == "many_to_many"
+		for _, mod := range modifiers {
+			key, value, _ := strings.Cut(strings.TrimSpace(mod), "=")
+			switch key = strings.ToLower(key); {
+			case (key == "foreignkey" || key == "foreign_key") && !toMany:
+				if value == "" {
+					fmt.Printf("Warning: missing foreign key name on field %s\n", field.Name)
+					continue
+				}
+				field.ForeignKey = ToPascalCase(value)
+			case key == "polymorphic" && !toMany:
+				if value == "" {
+					fmt.Printf("Warning: missing polymorphic relation name on field %s\n", field.Name)
+					continue
+				}
+				field.Polymorphic = ToPascalCase(value)
+			case key == "through" && toMany:
+				through, err := parseJoinModel(field.Name, value)
+				if err != nil {
+					return fmt.Errorf("invalid join model on field %s: %w", field.Name, err)
+				}
+				field.Through = through
+			default:
+				fmt.Printf("Warning: modifier %q is not supported on relation %s and was ignored\n", key, field.Name)
+			}
+		}
+		return nil
+	}
+
+	// Modifiers only make sense for plain columns and belongsTo foreign keys
+	if (field.IsRelation && field.RelationType != "belongs_to") || field.IsAttachment || field.Type == "translation.Field" {
+		fmt.Printf("Warning: modifiers are not supported on field %s and were ignored\n", field.Name)
+		return nil
+	}
+
+	for _, mod := range modifiers {
+		key, value, _ := strings.Cut(strings.TrimSpace(mod), "=")
+		if field.IsSecret && slices.Contains([]string{"unique", "index", "default"}, strings.ToLower(key)) {
+			// Hashes are salted, so they cannot be looked up or defaulted
+			fmt.Printf("Warning: modifier %q is not supported on secret field %s and was ignored\n", key, field.Name)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "required":
+			// State machines always hold a state, which requests cannot set
+			field.IsRequired = len(field.Transitions) == 0
+		case "unique":
+			field.IsUnique = true
+		case "index":
+			field.IsIndex = true
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				fmt.Printf("Warning: invalid size %q on field %s\n", value, field.Name)
+				continue
+			}
+			field.Size = size
+		case "default":
+			field.Default = value
+		case "":
+		default:
+			fmt.Printf("Warning: modifier %q is not supported on field %s and was ignored\n", key, field.Name)
+		}
+	}
+	if field.IsSecret {
+		// bcrypt only hashes the first 72 bytes, and its hashes do not fit a smaller column
+		field.Size = min(cmp.Or(field.Size, bcryptMaxLength), bcryptMaxLength)
+	}
+	if len(field.EnumValues) > 0 && !slices.Contains(field.EnumValues, field.Default) {
+		fmt.Printf("Warning: default %q on field %s is not one of its values, using %q\n", field.Default, field.Name, field.EnumValues[0])
+		field.Default = field.EnumValues[0]
+	}
+
+	var gormTags []string
+	if field.IsRequired || len(field.Transitions) > 0 {
+		gormTags = append(gormTags, "not null")
+	}
+	if field.IsUnique {
+		gormTags = append(gormTags, "uniqueIndex")
+	} else if field.IsIndex {
+		gormTags = append(gormTags, "index")
+	}
+	if field.Size > 0 && !field.IsSecret {
+		gormTags = append(gormTags, fmt.Sprintf("size:%d", field.Size))
+	}
+	if field.Default != "" {
+		gormTags = append(gormTags, "default:"+field.Default)
+	}
+	field.GORMTag = strings.Join(gormTags, ";")
+	field.GORM = field.GORMTag
+
+	var rules []string
+	if field.IsRequired {
+		rules = append(rules, "required")
+	}
+	// max counts runes, the bcrypt limit of secrets is in bytes and checked by the validators
+	if field.Size > 0 && field.Type == "string" && len(field.EnumValues) == 0 && !field.IsSecret {
+		rules = append(rules, fmt.Sprintf("max=%d", field.Size))
+	}
+	field.ValidateTag = strings.Join(rules, ",")
+	return nil
+}
+
+// parseJoinModel parses the join model of a through relation, e.g. Membership(role:string,level:int).
+// The keys are set by NewTemplateData, which knows the model declaring the relation.
+func parseJoinModel(fieldName, spec string) (*JoinModel, error) {
+	name, fieldSpec, hasFields := strings.Cut(spec, "(")
+	name = ToPascalCase(strings.TrimSpace(name))
+	if name == "" {
+		return nil, fmt.Errorf("missing join model name in %q", spec)
+	}
+
+	join := &JoinModel{
+		Name:      name,
+		TableName: ToSnakeCase(ToPlural(name)),
+		Link:      PluralizeClient.Singular(fieldName),
+	}
+	if !hasFields {
+		return join, nil
+	}
+	inner, ok := strings.CutSuffix(strings.TrimSpace(fieldSpec), ")")
+	if !ok {
+		return nil, fmt.Errorf("missing ) in %q", spec)
+	}
+	for _, def := range splitOutsideParens(inner, ',') {
+		if def = strings.TrimSpace(def); def == "" {
+			continue
+		}
+		field, err := ParseField(def)
+		if err != nil {
+			return nil, err
+		}
+		if field.IsRelation || field.IsAttachment || field.Type == "translation.Field" || len(field.EnumValues) > 0 {
+			return nil, fmt.Errorf("join field %s must be a plain field", field.Name)
+		}
+		join.Fields = append(join.Fields, field)
+	}
+	return join, nil
+}
+
+// SelfModel is the related model of a relation to the model being generated, as in
+// parent:belongsTo:self. NewTemplateData replaces it with the model name.
+const SelfModel = "Self"
+
 // parseBelongsToField handles belongsTo relationship fields
 func parseBelongsToField(fieldName string, parts []string, field Field) Field {
 	field.IsRelation = true
@@ -191,8 +602,10 @@ func parseBelongsToField(fieldName string, parts []string, field Field) Field {
 	field.Relationship = "belongs_to"
 
 	var relatedModel string
-	if len(parts) > 2 {
-		relatedModel = strings.TrimSpace(parts[2])
+	if strings.EqualFold(parts[1], "tree") {
+		relatedModel = SelfModel
+	} else if len(parts) > 2 {
+		relatedModel = ToPascalCase(strings.TrimSpace(parts[2]))
 	} else {
 		// Auto-detect from field name (remove _id suffix if present)
 		baseName := strings.TrimSuffix(fieldName, "_id")
@@ -202,8 +615,8 @@ func parseBelongsToField(fieldName string, parts []string, field Field) Field {
 	// For belongsTo, we need the foreign key field (ends with Id)
 	// If field name already ends with _id or Id, use it as is, otherwise add _id
 	var foreignKeyName string
-	lowerFieldName := strings.ToLower(fieldName)
-	if strings.HasSuffix(lowerFieldName, "_id") || strings.HasSuffix(lowerFieldName, "id") {
+	// Compare the Pascal case name so that e.g. "paid" is not mistaken for a foreign key
+	if strings.HasSuffix(strings.ToLower(fieldName), "_id") || strings.HasSuffix(ToPascalCase(fieldName), "Id") {
 		foreignKeyName = fieldName
 	} else {
 		foreignKeyName = fieldName + "_id"
@@ -282,6 +695,120 @@ func parseManyToManyField(fieldName string, parts []string, field Field) Field {
 	return field
 }
 
+// parsePolymorphicField handles polymorphic relationship fields, which belong to one of several
+// owner models, e.g. commentable:polymorphic:Post,Photo. NewTemplateData turns the relation into
+// its CommentableId and CommentableType columns.
+func parsePolymorphicField(fieldName string, parts []string, field Field) Field {
+	field.IsRelation = true
+	field.RelationType = "polymorphic"
+	field.Relationship = "polymorphic"
+	field.Polymorphic = field.Name
+
+	if len(parts) > 2 {
+		for _, owner := range strings.Split(parts[2], ",") {
+			if owner = strings.TrimSpace(owner); owner != "" {
+				field.PolymorphicOwners = append(field.PolymorphicOwners, ToPascalCase(owner))
+			}
+		}
+	}
+
+	return field
+}
+
+// enumValuePattern matches enum values, which also name their Go constants
+var enumValuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// parseEnumField handles enum fields, e.g. status:enum(draft,published,archived). The values
+// are stored as strings; the first one is the column default.
+func parseEnumField(spec string, field Field) Field {
+	inner, ok := strings.CutSuffix(strings.TrimSpace(spec), ")")
+	if !ok {
+		fmt.Printf("Warning: missing ) in enum of field %s\n", field.Name)
+	}
+
+	longest := 0
+	for _, value := range strings.Split(inner, ",") {
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			continue
+		case !enumValuePattern.MatchString(value):
+			fmt.Printf("Warning: enum value %q of field %s must be a letters, digits, _ and - word and was ignored\n", value, field.Name)
+			continue
+		case slices.Contains(field.EnumValues, value):
+			fmt.Printf("Warning: enum value %q of field %s is listed twice\n", value, field.Name)
+			continue
+		}
+		field.EnumValues = append(field.EnumValues, value)
+		longest = max(longest, len(value))
+	}
+	if len(field.EnumValues) == 0 {
+		fmt.Printf("Warning: enum field %s has no values and is generated as a string\n", field.Name)
+		field.Type = "string"
+		return field
+	}
+
+	// The Go type is named after the model by NewTemplateData
+	field.Type = "string"
+	field.Default = field.EnumValues[0]
+	field.Size = max(32, longest)
+	return field
+}
+
+// parseStateField handles state machine fields, e.g. status:state(draft->review->published, review->draft).
+// Each chain of states allows the moves between neighbours. The states are the values of an enum,
+// the first one being the initial state.
+func parseStateField(spec string, field Field) Field {
+	inner, ok := strings.CutSuffix(strings.TrimSpace(spec), ")")
+	if !ok {
+		fmt.Printf("Warning: missing ) in state of field %s\n", field.Name)
+	}
+
+	var states []string
+	var transitions []StateTransition
+	for _, chain := range strings.Split(inner, ",") {
+		path := strings.Split(chain, "->")
+		for i := range path {
+			path[i] = strings.TrimSpace(path[i])
+		}
+		if !slices.ContainsFunc(path, func(state string) bool { return state != "" }) {
+			continue
+		}
+		if i := slices.IndexFunc(path, func(state string) bool { return !enumValuePattern.MatchString(state) }); i >= 0 {
+			fmt.Printf("Warning: state %q of field %s must be a letters, digits, _ and - word, so %q was ignored\n", path[i], field.Name, strings.TrimSpace(chain))
+			continue
+		}
+		if len(path) == 1 {
+			fmt.Printf("Warning: state %q of field %s has no transition; write chains like draft->published\n", path[0], field.Name)
+		}
+
+		for i, state := range path {
+			if !slices.Contains(states, state) {
+				states = append(states, state)
+			}
+			if i == 0 {
+				continue
+			}
+			from := path[i-1]
+			j := slices.IndexFunc(transitions, func(t StateTransition) bool { return t.From == from })
+			if j < 0 {
+				transitions = append(transitions, StateTransition{From: from})
+				j = len(transitions) - 1
+			}
+			if !slices.Contains(transitions[j].To, state) {
+				transitions[j].To = append(transitions[j].To, state)
+			}
+		}
+	}
+
+	field = parseEnumField(strings.Join(states, ",")+")", field)
+	if len(transitions) == 0 && len(states) > 0 {
+		fmt.Printf("Warning: state field %s has no transitions and is generated as an enum\n", field.Name)
+	}
+	field.Transitions = transitions
+	return field
+}
+
 // parseAttachmentField handles attachment/file/image fields
 func parseAttachmentField(_ string, fieldType string, field Field) Field {
 	field.Type = "*storage.Attachment"
@@ -304,6 +831,9 @@ func parseAttachmentField(_ string, fieldType string, field Field) Field {
 func inferFieldType(fieldName string) string {
 	fieldName = strings.ToLower(fieldName)
 
+	if strings.Contains(fieldName, "password") {
+		return "password"
+	}
 	// Check for common patterns
 	if strings.HasSuffix(fieldName, "_id") {
 		return "uint"
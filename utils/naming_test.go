@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFieldModifiers(t *testing.T) {
+	tests := []struct {
+		def          string
+		required     bool
+		size         int
+		defaultValue string
+		gorm         string
+		validate     string
+	}{
+		{def: "title:string"},
+		{def: "title:string:required", required: true, gorm: "not null", validate: "required"},
+		{def: "email:string:required,unique", required: true, gorm: "not null;uniqueIndex", validate: "required"},
+		{def: "slug:string:index,size=100", size: 100, gorm: "index;size:100", validate: "max=100"},
+		{def: "title:string:Required,SIZE=20", required: true, size: 20, gorm: "not null;size:20", validate: "required,max=20"},
+		{def: "views:int:default=0", defaultValue: "0", gorm: "default:0"},
+		{def: "status:string:default=draft,size=16", size: 16, defaultValue: "draft", gorm: "size:16;default:draft", validate: "max=16"},
+		{def: "title:string:size=abc"},
+		{def: "title:string:size=-1"},
+		{def: "author:belongsTo:User:required", required: true, gorm: "not null", validate: "required"},
+		{def: "status:enum(draft,live):default=live", size: 32, defaultValue: "live", gorm: "size:32;default:live"},
+		{def: "status:enum(draft,live):default=gone", size: 32, defaultValue: "draft", gorm: "size:32;default:draft"},
+
+		// Secrets are capped at the 72 bytes bcrypt hashes, checked by the validators in bytes
+		{def: "password:password", size: 72},
+		{def: "password:password:size=32", size: 32},
+		{def: "password:password:size=100", size: 72},
+		{def: "password:password:required,unique", required: true, size: 72, gorm: "not null", validate: "required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			field, err := ParseField(tt.def)
+			if err != nil {
+				t.Fatalf("ParseField: %v", err)
+			}
+			if field.IsRequired != tt.required {
+				t.Errorf("IsRequired = %v, want %v", field.IsRequired, tt.required)
+			}
+			if field.Size != tt.size {
+				t.Errorf("Size = %d, want %d", field.Size, tt.size)
+			}
+			if field.Default != tt.defaultValue {
+				t.Errorf("Default = %q, want %q", field.Default, tt.defaultValue)
+			}
+			if field.GORMTag != tt.gorm {
+				t.Errorf("GORMTag = %q, want %q", field.GORMTag, tt.gorm)
+			}
+			if field.ValidateTag != tt.validate {
+				t.Errorf("ValidateTag = %q, want %q", field.ValidateTag, tt.validate)
+			}
+		})
+	}
+}
+
+func TestParseFieldRelationModifiers(t *testing.T) {
+	field, err := ParseField("posts:hasMany:Post:foreignKey=author_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.ForeignKey != "AuthorId" {
+		t.Errorf("ForeignKey = %q, want AuthorId", field.ForeignKey)
+	}
+
+	field, err = ParseField("members:manyToMany:User:through=Membership(role:string:required,level:int)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field.Through == nil || field.Through.Name != "Membership" || len(field.Through.Fields) != 2 {
+		t.Fatalf("Through = %+v, want Membership with 2 fields", field.Through)
+	}
+	if !field.Through.Fields[0].IsRequired {
+		t.Error("the role join field is not required")
+	}
+}
+
+func TestParseFieldRejectsUnknownModifiers(t *testing.T) {
+	tests := []struct {
+		def  string
+		want string
+	}{
+		{"title:string:requred", `unknown modifier "requred" on field Title`},
+		{"title:string:required,uniq", `unknown modifier "uniq" on field Title`},
+		{"views:int:max=10", `unknown modifier "max" on field Views`},
+		{"author:belongsTo:User:requird", `unknown modifier "requird" on field Author`},
+		{"posts:hasMany:Post:foreignKy=author_id", `unknown modifier "foreignKy" on field Posts`},
+		{"members:manyToMany:User:through=Membership(role:string:requred)", `invalid join model on field Members`},
+		{"members:manyToMany:User:through=(role:string)", `invalid join model on field Members`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.def, func(t *testing.T) {
+			_, err := ParseField(tt.def)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
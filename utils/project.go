@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IsProjectRoot reports whether dir is the root of a Base project: it has a go.mod, a core
+// directory and app/init.go
+func IsProjectRoot(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "go.mod")); err != nil || info.IsDir() {
+		return false
+	}
+	if info, err := os.Stat(filepath.Join(dir, "core")); err != nil || !info.IsDir() {
+		return false
+	}
+	if info, err := os.Stat(filepath.Join(dir, AppInitPath)); err != nil || info.IsDir() {
+		return false
+	}
+	return true
+}
+
+// FindProjectRoot walks up from dir to the root of the Base project containing it
+func FindProjectRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	for current := abs; ; current = filepath.Dir(current) {
+		if IsProjectRoot(current) {
+			return current, nil
+		}
+		if filepath.Dir(current) == current {
+			return "", fmt.Errorf("not inside a Base project: no go.mod with core/ and %s found in %s or any parent directory", AppInitPath, abs)
+		}
+	}
+}
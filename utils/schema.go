@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema describes a set of models to generate from a declarative YAML or JSON file
+type Schema struct {
+	Models []SchemaModel `yaml:"models"`
+}
+
+// SchemaModel describes a single module: its name, fields and relationships
+type SchemaModel struct {
+	Name      string        `yaml:"name"`
+	Fields    []SchemaField `yaml:"fields"`
+	Relations []SchemaField `yaml:"relations"`
+}
+
+// SchemaField describes a field or relationship. It can be written either as the
+// command line shorthand ("title:string:required") or as a mapping with options.
+type SchemaField struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Model       string   `yaml:"model"` // Related model for relationships, the owners of a polymorphic one
+	Required    bool     `yaml:"required"`
+	Unique      bool     `yaml:"unique"`
+	Index       bool     `yaml:"index"`
+	Size        int      `yaml:"size"`
+	Default     string   `yaml:"default"`
+	Through     string   `yaml:"through"`     // Join model of a manyToMany, e.g. Membership(role:string)
+	Polymorphic string   `yaml:"polymorphic"` // Polymorphic relation of a hasMany, e.g. commentable
+	Values      []string `yaml:"values"`      // Values of an enum, the first one being the default unless default is set
+	Transitions []string `yaml:"transitions"` // Chains of a state machine, e.g. draft->review->published
+
+	// Spec holds the shorthand definition when the field is written as a plain string
+	Spec string `yaml:"-"`
+}
+
+// UnmarshalYAML accepts both the shorthand string form and the mapping form
+func (f *SchemaField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Spec = strings.TrimSpace(node.Value)
+		return nil
+	}
+
+	type plain SchemaField
+	return node.Decode((*plain)(f))
+}
+
+// Definition returns the field in the "name:type[:Model][:modifiers]" syntax understood by ParseField
+func (f SchemaField) Definition() string {
+	if f.Spec != "" {
+		return f.Spec
+	}
+
+	parts := []string{f.Name}
+	if len(f.Transitions) > 0 {
+		parts = append(parts, "state("+strings.Join(f.Transitions, ",")+")")
+	} else if len(f.Values) > 0 {
+		parts = append(parts, "enum("+strings.Join(f.Values, ",")+")")
+	} else if f.Type != "" {
+		parts = append(parts, f.Type)
+	}
+	if f.Model != "" {
+		parts = append(parts, f.Model)
+	}
+
+	var modifiers []string
+	if f.Required {
+		modifiers = append(modifiers, "required")
+	}
+	if f.Unique {
+		modifiers = append(modifiers, "unique")
+	}
+	if f.Index {
+		modifiers = append(modifiers, "index")
+	}
+	if f.Size > 0 {
+		modifiers = append(modifiers, fmt.Sprintf("size=%d", f.Size))
+	}
+	if f.Default != "" {
+		modifiers = append(modifiers, "default="+f.Default)
+	}
+	if f.Through != "" {
+		modifiers = append(modifiers, "through="+f.Through)
+	}
+	if f.Polymorphic != "" {
+		modifiers = append(modifiers, "polymorphic="+f.Polymorphic)
+	}
+	if len(modifiers) > 0 {
+		// Modifiers are always the last segment, so an inferred type must be spelled out
+		if f.Type == "" {
+			parts = append(parts, inferFieldType(f.Name))
+		}
+		parts = append(parts, strings.Join(modifiers, ","))
+	}
+
+	return strings.Join(parts, ":")
+}
+
+// FieldDefs returns the definitions of all fields followed by all relationships
+func (m SchemaModel) FieldDefs() []string {
+	defs := make([]string, 0, len(m.Fields)+len(m.Relations))
+	for _, f := range m.Fields {
+		defs = append(defs, f.Definition())
+	}
+	for _, r := range m.Relations {
+		defs = append(defs, r.Definition())
+	}
+	return defs
+}
+
+// LoadSchema reads and validates a schema file. JSON is accepted as it is a subset of YAML.
+func LoadSchema(path string) (*Schema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", path, err)
+	}
+
+	if err := schema.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+
+	return &schema, nil
+}
+
+// Validate checks that every model and field is named and that model names are unique
+func (s *Schema) Validate() error {
+	if len(s.Models) == 0 {
+		return fmt.Errorf("no models defined")
+	}
+
+	seen := make(map[string]bool)
+	for i, model := range s.Models {
+		if model.Name == "" {
+			return fmt.Errorf("model #%d has no name", i+1)
+		}
+
+		key := ToPascalCase(model.Name)
+		if seen[key] {
+			return fmt.Errorf("model %s is defined more than once", model.Name)
+		}
+		seen[key] = true
+
+		for _, field := range append(append([]SchemaField{}, model.Fields...), model.Relations...) {
+			if field.Spec == "" && field.Name == "" {
+				return fmt.Errorf("model %s has a field without a name", model.Name)
+			}
+		}
+		for _, rel := range model.Relations {
+			if rel.Spec == "" && !IsRelationshipType(rel.Type) {
+				return fmt.Errorf("relation %s on model %s has unknown type %q", rel.Name, model.Name, rel.Type)
+			}
+		}
+	}
+
+	return nil
+}
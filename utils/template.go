@@ -1,28 +1,92 @@
 package utils
 
 import (
-	_ "embed"
+	"bytes"
+	"embed"
 	"fmt"
+	"go/format"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
+
+	"golang.org/x/tools/imports"
 )
 
-//go:embed templates/model.tmpl
-var modelTemplate string
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// TemplateOverrideDir is the project directory searched for templates before the embedded ones
+var TemplateOverrideDir = filepath.Join(".base", "templates")
+
+// EmbeddedTemplateNames returns the names of the templates built into the CLI
+func EmbeddedTemplateNames() []string {
+	entries, err := embeddedTemplates.ReadDir("templates")
+	if err != nil {
+		return nil
+	}
 
-//go:embed templates/controller.tmpl
-var controllerTemplate string
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
 
-//go:embed templates/service.tmpl
-var serviceTemplate string
+// EmbeddedTemplate returns the content of a template built into the CLI
+func EmbeddedTemplate(name string) (string, error) {
+	content, err := embeddedTemplates.ReadFile(path.Join("templates", name))
+	if err != nil {
+		return "", fmt.Errorf("unknown template: %s", name)
+	}
+	return string(content), nil
+}
 
-//go:embed templates/module.tmpl
-var moduleTemplate string
+// LoadTemplate returns a template, preferring the project's .base/templates override over
+// the embedded version. The second return value is the path it was loaded from, or
+// "embedded" for built-in templates.
+func LoadTemplate(name string) (string, string, error) {
+	overridePath := filepath.Join(TemplateOverrideDir, name)
+	content, err := os.ReadFile(overridePath)
+	if err == nil {
+		return string(content), overridePath, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", "", fmt.Errorf("failed to read %s: %w", overridePath, err)
+	}
 
-//go:embed templates/validator.tmpl
-var validatorTemplate string
+	embedded, err := EmbeddedTemplate(name)
+	if err != nil {
+		return "", "", err
+	}
+	return embedded, "embedded", nil
+}
+
+// EjectTemplate copies an embedded template into the project's override directory.
+// Existing files are only replaced when overwrite is set.
+func EjectTemplate(name string, overwrite bool) (string, error) {
+	content, err := EmbeddedTemplate(name)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(TemplateOverrideDir, name)
+	if _, err := os.Stat(target); err == nil && !overwrite {
+		return "", fmt.Errorf("%s already exists", target)
+	}
+
+	if err := os.MkdirAll(TemplateOverrideDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", TemplateOverrideDir, err)
+	}
+	if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return target, nil
+}
 
 // TemplateData contains all data needed for template generation
 type TemplateData struct {
@@ -33,6 +97,9 @@ type TemplateData struct {
 	Fields []Field
 
 	// Computed properties
+	HasTree               bool
+	HasEnums              bool
+	HasStateMachine       bool
 	HasRelations          bool
 	HasBelongsTo          bool
 	HasHasMany            bool
@@ -53,7 +120,7 @@ type TemplateData struct {
 }
 
 // NewTemplateData creates template data from model name and field definitions
-func NewTemplateData(modelName string, fieldDefs []string) *TemplateData {
+func NewTemplateData(modelName string, fieldDefs []string) (*TemplateData, error) {
 	nc := NewNamingConvention(modelName)
 	td := &TemplateData{
 		NamingConvention: nc,
@@ -63,7 +130,34 @@ func NewTemplateData(modelName string, fieldDefs []string) *TemplateData {
 
 	// Generate field structs using centralized parsing
 	for _, fieldDef := range fieldDefs {
-		field := ParseField(fieldDef)
+		field, err := ParseField(fieldDef)
+		if err != nil {
+			return nil, err
+		}
+		if field.Relationship == "belongs_to" && (field.RelatedModel == SelfModel || field.RelatedModel == nc.Model) {
+			field.RelatedModel = nc.Model
+			td.markTree(&field)
+		}
+		if field.Through != nil {
+			// GORM names the keys of a self-referential join after the relation, e.g. FriendId
+			field.Through.OwnerKey = nc.Model + "Id"
+			field.Through.RelatedKey = field.RelatedModel + "Id"
+			if field.RelatedModel == nc.Model {
+				field.Through.RelatedKey = field.Through.Link + "Id"
+			}
+		}
+
+		if len(field.EnumValues) > 0 {
+			field.Type = nc.Model + field.Name
+			td.HasEnums = true
+		}
+		if len(field.Transitions) > 0 {
+			td.markStateMachine(&field)
+		}
+		if field.RelationType == "polymorphic" {
+			td.Fields = append(td.Fields, polymorphicColumns(nc, field)...)
+			continue
+		}
 
 		// Handle belongsTo relationships - need both foreign key and relationship object
 		if field.Relationship == "belongs_to" {
@@ -97,7 +191,110 @@ func NewTemplateData(modelName string, fieldDefs []string) *TemplateData {
 	// Add standard imports
 	td.addStandardImports()
 
-	return td
+	return td, nil
+}
+
+// markTree makes the first belongsTo pointing at the model itself its tree relation. Roots have
+// no parent, so the key is nullable and left out of generated test requests. Further
+// self-references stay plain belongsTo relations.
+func (td *TemplateData) markTree(field *Field) {
+	if td.HasTree {
+		return
+	}
+	td.HasTree = true
+	field.IsTree = true
+	field.Type = "*uint"
+	field.TestValue, field.UpdateTestValue, field.TestValueWithIndex, field.TestValueUnique = "", "", "", ""
+}
+
+// markStateMachine makes the first state field of the model its state machine. Further state
+// fields lose their transitions and are generated as enums, since Transition moves one field.
+func (td *TemplateData) markStateMachine(field *Field) {
+	if !td.HasStateMachine {
+		td.HasStateMachine = true
+		return
+	}
+	fmt.Printf("Warning: %s is the second state field of %s and is generated as an enum without transitions\n", field.Name, td.Model)
+	field.Transitions = nil
+	setTestValues(field)
+}
+
+// StateField returns the state machine field among fields, or nil when the model has none
+func StateField(fields []Field) *Field {
+	for i := range fields {
+		if len(fields[i].Transitions) > 0 {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// TreeField returns the tree relation among fields, or nil when the model is not a tree
+func TreeField(fields []Field) *Field {
+	for i := range fields {
+		if fields[i].IsTree {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// polymorphicColumns returns the plain columns a polymorphic relation is stored in, e.g.
+// CommentableId and CommentableType. Both share an index, since comments are looked up by owner.
+func polymorphicColumns(nc *NamingConvention, relation Field) []Field {
+	index := fmt.Sprintf("index:idx_%s_%s", nc.TableName, ToSnakeCase(relation.Name))
+	column := func(suffix, goType string, size int) Field {
+		field := Field{
+			Name:        relation.Name + suffix,
+			Type:        goType,
+			JSONTag:     ToSnakeCase(relation.Name + suffix),
+			Size:        size,
+			IsRequired:  relation.IsRequired,
+			IsIndex:     true,
+			Polymorphic: relation.Name,
+		}
+		field.JSONName = field.JSONTag
+		field.DBName = field.JSONTag
+
+		var gormTags, rules []string
+		if field.IsRequired {
+			gormTags = append(gormTags, "not null")
+			rules = append(rules, "required")
+		}
+		if size > 0 {
+			gormTags = append(gormTags, fmt.Sprintf("size:%d", size))
+			rules = append(rules, fmt.Sprintf("max=%d", size))
+		}
+		field.GORMTag = strings.Join(append(gormTags, index), ";")
+		field.GORM = field.GORMTag
+		field.ValidateTag = strings.Join(rules, ",")
+		setTestValues(&field)
+		return field
+	}
+
+	ownerId, ownerType := column("Id", "uint", 0), column("Type", "string", 64)
+	ownerType.PolymorphicOwners = relation.PolymorphicOwners
+	if len(ownerType.PolymorphicOwners) > 0 {
+		// The type holds the owner's table name, as GORM writes it for the owners' hasMany
+		owners := make([]string, len(ownerType.PolymorphicOwners))
+		for i, owner := range ownerType.PolymorphicOwners {
+			owners[i] = fmt.Sprintf("%q", ToSnakeCase(ToPlural(owner)))
+		}
+		ownerType.TestValue, ownerType.TestValueWithIndex, ownerType.TestValueUnique = owners[0], owners[0], owners[0]
+		ownerType.UpdateTestValue = owners[len(owners)-1]
+	}
+	return []Field{ownerId, ownerType}
+}
+
+// PolymorphicField returns the Type column of the polymorphic relation among fields, or nil
+// when the model has none. Only the first relation gets the list by owner methods.
+func PolymorphicField(fields []Field) *Field {
+	for i := range fields {
+		if fields[i].Polymorphic != "" && fields[i].Type == "string" {
+			return &fields[i]
+		}
+	}
+	return nil
 }
 
 // updateComputedProperties updates computed properties based on field
@@ -147,6 +344,7 @@ func (td *TemplateData) addStandardImports() {
 	imports["gorm.io/gorm"] = true
 
 	// Check fields for additional imports
+	modulePath := ModulePath()
 	for _, field := range td.Fields {
 		switch field.Type {
 		case "time.Time":
@@ -154,9 +352,9 @@ func (td *TemplateData) addStandardImports() {
 		case "datatypes.JSON":
 			imports["gorm.io/datatypes"] = true
 		case "*storage.Attachment":
-			imports["base/core/storage"] = true
+			imports[modulePath+"/core/storage"] = true
 		case "translation.Field":
-			imports["base/core/translation"] = true
+			imports[modulePath+"/core/translation"] = true
 		}
 	}
 
@@ -167,10 +365,21 @@ func (td *TemplateData) addStandardImports() {
 	}
 }
 
-// HasFieldType checks if any field has the specified type
+// HasFieldType checks if any field, including the fields of join models, has the specified type
 func HasFieldType(fields []Field, fieldType string) bool {
 	for _, field := range fields {
-		if field.Type == fieldType {
+		if field.Type == fieldType || (field.Through != nil && HasFieldType(field.Through.Fields, fieldType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRelationType checks if any field is a relation of the given canonical type
+// (e.g. "belongs_to"), or any relation at all when relationType is empty
+func HasRelationType(fields []Field, relationType string) bool {
+	for _, field := range fields {
+		if field.IsRelation && (relationType == "" || field.RelationType == relationType) {
 			return true
 		}
 	}
@@ -295,36 +504,75 @@ func inferFieldTypeCompat(fieldName string) string {
 
 // GenerateFileFromTemplate generates a file from embedded template (for backward compatibility)
 func GenerateFileFromTemplate(dir, filename, templateName string, naming *NamingConvention, fields []Field) {
-	// Convert Field slice to embedded template data
-	var tmplContent string
-	switch templateName {
-	case "model.tmpl":
-		tmplContent = modelTemplate
-	case "controller.tmpl":
-		tmplContent = controllerTemplate
-	case "service.tmpl":
-		tmplContent = serviceTemplate
-	case "module.tmpl":
-		tmplContent = moduleTemplate
-	case "validator.tmpl":
-		tmplContent = validatorTemplate
-	default:
-		fmt.Printf("Unknown template: %s\n", templateName)
+	content, err := RenderTemplate(templateName, naming, fields)
+	if err != nil {
+		fmt.Printf("Error rendering template %s: %v\n", templateName, err)
+		return
+	}
+
+	// Create output directory
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		fmt.Printf("Error creating directory: %v\n", err)
+		return
+	}
+
+	// Create output file
+	outputFile := filepath.Join(dir, filename)
+	if err := os.WriteFile(outputFile, content, 0644); err != nil {
+		fmt.Printf("Error creating file %s: %v\n", outputFile, err)
 		return
 	}
 
-	// Create template with functions
-	funcMap := template.FuncMap{
+	fmt.Printf("Generated %s\n", outputFile)
+}
+
+// RenderTemplate renders a template in memory, using the project override when present.
+// The output is gofmt-formatted when it parses; otherwise the raw output is returned so
+// it can still be inspected.
+func RenderTemplate(templateName string, naming *NamingConvention, fields []Field) ([]byte, error) {
+	tmplContent, source, err := LoadTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := executeTemplate(templateName, source, tmplContent, naming, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if formatted, err := format.Source(output); err == nil {
+		return formatted, nil
+	}
+	return output, nil
+}
+
+// FormatGoFile formats Go source that will be written to path and fixes its imports the way
+// goimports does: unused imports are removed and missing ones added. Source that does not
+// parse is returned unchanged with the error, so it can still be written and inspected.
+func FormatGoFile(path string, src []byte) ([]byte, error) {
+	formatted, err := imports.Process(path, src, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return src, fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	return formatted, nil
+}
+
+// templateFuncMap returns the functions available to every generator template
+func templateFuncMap() template.FuncMap {
+	return template.FuncMap{
 		"toLower":      strings.ToLower,
 		"toTitle":      ToTitle,
 		"ToSnakeCase":  ToSnakeCase,
 		"ToPascalCase": ToPascalCase,
+		"ToCamelCase":  ToCamelCase,
 		"ToKebabCase":  ToKebabCase,
 		"ToPlural":     ToPlural,
 		"TrimIdSuffix": TrimIdSuffix,
 		"hasPrefix":    strings.HasPrefix,
 		"hasSuffix":    strings.HasSuffix,
 		"contains":     strings.Contains,
+		"join":         strings.Join,
 		"eq":           func(a, b interface{}) bool { return a == b },
 		"slice": func(s string, start, end int) string {
 			if start >= len(s) {
@@ -339,32 +587,20 @@ func GenerateFileFromTemplate(dir, filename, templateName string, naming *Naming
 			return HasFieldType(fields, fieldType)
 		},
 	}
+}
 
-	tmpl, err := template.New(templateName).Funcs(funcMap).Parse(tmplContent)
-	if err != nil {
-		fmt.Printf("Error parsing template %s: %v\n", templateName, err)
-		return
-	}
-
-	// Create output directory
-	err = os.MkdirAll(dir, 0755)
-	if err != nil {
-		fmt.Printf("Error creating directory: %v\n", err)
-		return
-	}
-
-	// Create output file
-	outputFile := filepath.Join(dir, filename)
-	f, err := os.Create(outputFile)
+// executeTemplate parses and executes template content with the module data.
+// source is only used in error messages.
+func executeTemplate(templateName, source, tmplContent string, naming *NamingConvention, fields []Field) ([]byte, error) {
+	tmpl, err := template.New(templateName).Funcs(templateFuncMap()).Parse(tmplContent)
 	if err != nil {
-		fmt.Printf("Error creating file %s: %v\n", outputFile, err)
-		return
+		return nil, fmt.Errorf("error parsing template %s (%s): %w", templateName, source, err)
 	}
-	defer f.Close()
 
 	// Execute template with data structure
 	data := struct {
 		*NamingConvention
+		ModulePath            string
 		Fields                []Field
 		HasImageField         bool
 		HasTranslatableFields bool
@@ -376,27 +612,52 @@ func GenerateFileFromTemplate(dir, filename, templateName string, naming *Naming
 		HasHasMany            bool
 		HasHasOne             bool
 		HasManyToMany         bool
+		TreeField             *Field
+		HasThrough            bool
+		PolymorphicField      *Field
+		HasEnums              bool
+		StateField            *Field
+		HasSecrets            bool
+		Formats               map[string]bool
 	}{
 		NamingConvention:      naming,
+		ModulePath:            ModulePath(),
 		Fields:                fields,
 		HasImageField:         HasImageField(fields),
 		HasTranslatableFields: HasFieldType(fields, "translation.Field"),
 		HasSoftDelete:         HasFieldType(fields, "gorm.DeletedAt"),
 		HasTimestamps:         HasFieldType(fields, "time.Time"),
 		HasAttachments:        HasFieldType(fields, "*storage.Attachment"),
-		HasRelations:          HasFieldType(fields, "*models."),
-		HasBelongsTo:          HasFieldType(fields, "belongsTo"),
-		HasHasMany:            HasFieldType(fields, "hasMany"),
-		HasHasOne:             HasFieldType(fields, "hasOne"),
-		HasManyToMany:         HasFieldType(fields, "manyToMany"),
+		HasRelations:          HasRelationType(fields, ""),
+		HasBelongsTo:          HasRelationType(fields, "belongs_to"),
+		HasHasMany:            HasRelationType(fields, "has_many"),
+		HasHasOne:             HasRelationType(fields, "has_one"),
+		HasManyToMany:         HasRelationType(fields, "many_to_many"),
+		TreeField:             TreeField(fields),
+		HasThrough:            slices.ContainsFunc(fields, func(f Field) bool { return f.Through != nil }),
+		PolymorphicField:      PolymorphicField(fields),
+		HasEnums:              slices.ContainsFunc(fields, func(f Field) bool { return len(f.EnumValues) > 0 }),
+		StateField:            StateField(fields),
+		HasSecrets:            slices.ContainsFunc(fields, func(f Field) bool { return f.IsSecret }),
+		Formats:               fieldFormats(fields),
 	}
 
-	if err := tmpl.Execute(f, data); err != nil {
-		fmt.Printf("Error executing template: %v\n", err)
-		return
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error executing template %s (%s): %w", templateName, source, err)
 	}
+	return buf.Bytes(), nil
+}
 
-	fmt.Printf("Generated %s\n", outputFile)
+// fieldFormats returns the formats the validators check, e.g. Formats.email when a field is an email
+func fieldFormats(fields []Field) map[string]bool {
+	formats := make(map[string]bool)
+	for _, field := range fields {
+		if field.Format != "" {
+			formats[field.Format] = true
+		}
+	}
+	return formats
 }
 
 // HasImageField checks if any field has image type
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// CompileError is a parse or type error found while verifying generated code
+type CompileError struct {
+	Path    string // File the error is in, relative to the project root when possible
+	Line    int
+	Column  int
+	Message string
+
+	// Template and Field trace the error back to what generated the line, when known
+	Template string
+	Field    string
+}
+
+// String formats the error as "path:line:col: message", followed by its origin
+func (e CompileError) String() string {
+	position := e.Path
+	if e.Line > 0 {
+		position = fmt.Sprintf("%s:%d:%d", e.Path, e.Line, e.Column)
+	}
+	message := fmt.Sprintf("%s: %s", position, e.Message)
+
+	switch {
+	case e.Template != "" && e.Field != "":
+		message += fmt.Sprintf(" (from %s, field %s)", e.Template, e.Field)
+	case e.Template != "":
+		message += fmt.Sprintf(" (from %s)", e.Template)
+	}
+	return message
+}
+
+// VerifyChanges type-checks every package containing a Go file of the change set, including
+// its tests, and returns the errors found. Errors in generated files are traced back to the
+// template and field that produced them.
+func VerifyChanges(changes *ChangeSet) ([]CompileError, error) {
+	dirs := make(map[string]bool)
+	for _, change := range changes.Changes {
+		if strings.HasSuffix(change.Path, ".go") {
+			dirs["./"+filepath.ToSlash(filepath.Dir(change.Path))] = true
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+
+	var patterns []string
+	for dir := range dirs {
+		patterns = append(patterns, dir)
+	}
+	sort.Strings(patterns)
+
+	errs, err := CheckPackages(patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range errs {
+		change := changes.Get(errs[i].Path)
+		if change == nil || change.Template == "" {
+			continue
+		}
+		errs[i].Template = change.Template
+		errs[i].Field = fieldAtLine(change.After, errs[i], change.Fields)
+	}
+	return errs, nil
+}
+
+// CheckPackages loads the packages matching patterns with the Go type checker and returns
+// their parse and type errors
+func CheckPackages(patterns ...string) ([]CompileError, error) {
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	wd, _ := os.Getwd()
+	seen := make(map[string]bool)
+	var errs []CompileError
+
+	// With Tests set a package is loaded up to three times, so the same error is reported repeatedly
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, pkgErr := range pkg.Errors {
+			key := pkgErr.Pos + pkgErr.Msg
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			errs = append(errs, newCompileError(wd, pkgErr))
+		}
+	})
+	return errs, nil
+}
+
+// newCompileError splits a packages error position ("file:line:col") into its parts
+func newCompileError(wd string, pkgErr packages.Error) CompileError {
+	compileErr := CompileError{Path: pkgErr.Pos, Message: pkgErr.Msg}
+	if pkgErr.Pos == "" || pkgErr.Pos == "-" {
+		compileErr.Path = "-"
+		return compileErr
+	}
+
+	parts := strings.Split(pkgErr.Pos, ":")
+	if len(parts) >= 3 {
+		line, lineErr := strconv.Atoi(parts[len(parts)-2])
+		column, columnErr := strconv.Atoi(parts[len(parts)-1])
+		if lineErr == nil && columnErr == nil {
+			compileErr.Path = strings.Join(parts[:len(parts)-2], ":")
+			compileErr.Line = line
+			compileErr.Column = column
+		}
+	}
+	if rel, err := filepath.Rel(wd, compileErr.Path); err == nil && !strings.HasPrefix(rel, "..") {
+		compileErr.Path = rel
+	}
+	return compileErr
+}
+
+// fieldAtLine returns the name of the field referenced on the error's line or in its message
+func fieldAtLine(content []byte, compileErr CompileError, fields []Field) string {
+	lines := bytes.Split(content, []byte("\n"))
+	text := compileErr.Message
+	if compileErr.Line > 0 && compileErr.Line <= len(lines) {
+		text = string(lines[compileErr.Line-1]) + "\n" + text
+	}
+
+	for _, field := range fields {
+		for _, name := range []string{field.Name, TrimIdSuffix(field.Name), field.RelatedModel} {
+			if name != "" && regexp.MustCompile(`\b`+regexp.QuoteMeta(name)+`\b`).MatchString(text) {
+				return field.Name
+			}
+		}
+	}
+	return ""
+}